@@ -0,0 +1,144 @@
+package zfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// OpenAppender 的可选项，nil 表示全部使用默认值
+type AppendOptions struct {
+	// 写缓冲区大小，<=0 时不缓冲，每次 Write 直接写入文件
+	BufferSize int
+	// 为 true 时每次写入文件后 fsync，缓冲写入时在 Flush 后 fsync
+	Sync bool
+	// 新建文件的权限，为 0 时使用 0666（去掉 umask）
+	Perm os.FileMode
+}
+
+// 在文件末尾追加写入的句柄，可并发使用
+// 实现 io.WriteCloser，并可作为 zapcore.WriteSyncer 使用
+type Appender struct {
+	mu   sync.Mutex
+	file io.WriteCloser
+	sync func() error // 为 nil 时表示底层文件不支持 fsync
+	buf  *bufio.Writer
+	opts AppendOptions
+}
+
+// 打开文件用于追加写入，文件或所在目录不存在时自动创建
+// a, _ := OpenAppender("logs/app.log", &AppendOptions{BufferSize: 64 * 1024})
+// defer a.Close()
+func OpenAppender(path string, opts *AppendOptions) (*Appender, error) {
+	return defaultFS.OpenAppender(path, opts)
+}
+
+// 打开文件用于追加写入，文件或所在目录不存在时自动创建
+// 启用透明压缩时不能打开压缩文件，返回 ErrCompressionUnsupported，可改用 WriteAppend
+func (f *FS) OpenAppender(path string, opts *AppendOptions) (*Appender, error) {
+	if f.compression && compressionByName(path) != CompressionNone {
+		return nil, fmt.Errorf("%w: cannot open %s for appending", ErrCompressionUnsupported, path)
+	}
+	return f.openAppender(path, opts)
+}
+
+func (f *FS) openAppender(path string, opts *AppendOptions) (*Appender, error) {
+	a := &Appender{}
+	if opts != nil {
+		a.opts = *opts
+	}
+	perm := a.opts.Perm
+	if perm == 0 {
+		perm = 0666
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if name, ok := f.osPath(path); ok {
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(name, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+		a.file, a.sync = file, file.Sync
+	} else {
+		if err := f.fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return nil, err
+		}
+		file, err := f.fs.OpenFile(path, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+		a.file = file
+		if s, ok := file.(interface{ Sync() error }); ok {
+			a.sync = s.Sync
+		}
+	}
+	if a.opts.BufferSize > 0 {
+		a.buf = bufio.NewWriterSize(a.file, a.opts.BufferSize)
+	}
+	return a, nil
+}
+
+// 追加写入，缓冲写入时数据在 Flush 或 Close 后才写入文件
+func (a *Appender) Write(p []byte) (n int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.buf != nil {
+		return a.buf.Write(p)
+	}
+	if n, err = a.file.Write(p); err != nil {
+		return n, err
+	}
+	if a.opts.Sync {
+		err = a.fsync()
+	}
+	return n, err
+}
+
+// 将缓冲区中的数据写入文件，Sync 选项开启时随后 fsync
+func (a *Appender) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.flush(a.opts.Sync)
+}
+
+// 将缓冲区中的数据写入文件并 fsync
+func (a *Appender) Sync() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.flush(true)
+}
+
+// 写入缓冲区中的数据后关闭文件
+func (a *Appender) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.flush(a.opts.Sync)
+	if e := a.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (a *Appender) flush(sync bool) error {
+	if a.buf != nil {
+		if err := a.buf.Flush(); err != nil {
+			return err
+		}
+	}
+	if sync {
+		return a.fsync()
+	}
+	return nil
+}
+
+func (a *Appender) fsync() error {
+	if a.sync == nil {
+		return nil
+	}
+	return a.sync()
+}
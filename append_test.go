@@ -0,0 +1,41 @@
+package zfile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestWriteAppend(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "logs", "app.log")
+	assert.Nil(t, WriteAppend(name, []byte("hello ")))
+	assert.Nil(t, WriteAppend(name, []byte("china"), true))
+	content, _ := ReadFile(name)
+	assert.Equal(t, "hello china", content)
+
+	fs := NewFS(memfs.New())
+	assert.Nil(t, fs.WriteAppend("logs/app.log", []byte("a")))
+	assert.Nil(t, fs.WriteAppend("logs/app.log", []byte("b")))
+	content, _ = fs.ReadFile("logs/app.log")
+	assert.Equal(t, "ab", content)
+}
+
+func TestAppender(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	a, err := OpenAppender(name, &AppendOptions{BufferSize: 1024, Sync: true})
+	assert.Nil(t, err)
+	a.Write([]byte("line1\n"))
+	size, _ := FileSize(name)
+	assert.Equal(t, int64(0), size)
+
+	assert.Nil(t, a.Flush())
+	size, _ = FileSize(name)
+	assert.Equal(t, int64(6), size)
+
+	a.Write([]byte("line2\n"))
+	assert.Nil(t, a.Close())
+	lines, _ := ReadFileLines(name)
+	assert.Equal(t, []string{"line1", "line2"}, lines)
+}
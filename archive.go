@@ -0,0 +1,621 @@
+package zfile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// 压缩包的格式
+type ArchiveFormat string
+
+const (
+	// 按文件扩展名判断，解压时优先按文件头判断
+	ArchiveAuto   ArchiveFormat = ""
+	ArchiveZip    ArchiveFormat = "zip"
+	ArchiveTar    ArchiveFormat = "tar"
+	ArchiveTarGz  ArchiveFormat = "tar.gz"
+	ArchiveTarZst ArchiveFormat = "tar.zst"
+)
+
+// 解压时条目的路径位于目标目录之外，如包含 ".." 的绝对路径，或需经过符号链接写入
+var ErrUnsafeArchivePath = errors.New("archive entry escapes the target directory")
+
+// Archive、Extract 的可选项，nil 表示全部使用默认值
+type ArchiveOptions struct {
+	// 过滤规则，按条目相对根目录的路径匹配，被排除的目录及其中的内容不打包或不解压
+	Filter *Filter
+}
+
+// 将目录中的内容打包为 dst，format 为 ArchiveAuto 时按 dst 的扩展名判断格式
+// 打包时保留文件的权限和修改时间，符号链接按 FS 的符号链接策略处理，SymlinkKeep 时保存链接本身
+// Archive("./dist", "./dist.tar.gz", ArchiveAuto, &ArchiveOptions{Filter: NewFilter().Exclude("*.map")})
+func Archive(srcDir, dst string, format ArchiveFormat, opts *ArchiveOptions) error {
+	return defaultFS.Archive(srcDir, dst, format, opts)
+}
+
+// 将目录中的内容打包为 dst，失败时删除不完整的 dst
+func (f *FS) Archive(srcDir, dst string, format ArchiveFormat, opts *ArchiveOptions) error {
+	if format == ArchiveAuto {
+		if format = archiveFormatByName(dst); format == ArchiveAuto {
+			return fmt.Errorf("unknown archive format: %s", dst)
+		}
+	}
+	if !f.IsDir(srcDir) {
+		return fmt.Errorf("given path does not exist: %s", srcDir)
+	}
+	file, err := f.create(dst)
+	if err != nil {
+		return err
+	}
+	// 压缩包位于 srcDir 中时不打包其自身
+	err = f.writeArchive(file, srcDir, filepath.Clean(dst), format, opts)
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		f.fs.Remove(dst)
+	}
+	return err
+}
+
+// 将目录中的内容以流的方式打包写入 w，如直接写入网络连接，format 不能为 ArchiveAuto
+func WriteArchive(w io.Writer, srcDir string, format ArchiveFormat, opts *ArchiveOptions) error {
+	return defaultFS.WriteArchive(w, srcDir, format, opts)
+}
+
+// 将目录中的内容以流的方式打包写入 w
+func (f *FS) WriteArchive(w io.Writer, srcDir string, format ArchiveFormat, opts *ArchiveOptions) error {
+	if !f.IsDir(srcDir) {
+		return fmt.Errorf("given path does not exist: %s", srcDir)
+	}
+	return f.writeArchive(w, srcDir, "", format, opts)
+}
+
+func (f *FS) writeArchive(w io.Writer, srcDir, skip string, format ArchiveFormat, opts *ArchiveOptions) error {
+	if opts == nil {
+		opts = &ArchiveOptions{}
+	}
+	var aw archiveWriter
+	switch format {
+	case ArchiveZip:
+		aw = &zipArchiveWriter{zw: zip.NewWriter(w)}
+	case ArchiveTar:
+		aw = &tarArchiveWriter{tw: tar.NewWriter(w)}
+	case ArchiveTarGz:
+		gw := gzip.NewWriter(w)
+		aw = &tarArchiveWriter{tw: tar.NewWriter(gw), compressor: gw}
+	case ArchiveTarZst:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		aw = &tarArchiveWriter{tw: tar.NewWriter(zw), compressor: zw}
+	default:
+		return fmt.Errorf("unknown archive format: %s", format)
+	}
+	err := f.Walk(srcDir, func(name string, fi os.FileInfo, err error) error {
+		if errors.Is(err, ErrSymlinkLoop) { // 忽略构成循环的链接
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, name)
+		if err != nil || rel == "." || filepath.Clean(name) == skip {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !opts.Filter.Allow(rel, fi) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case fi.IsDir():
+			return aw.writeDir(rel+"/", fi)
+		case isSymlink(fi):
+			link, err := f.fs.Readlink(name)
+			if err != nil {
+				return err
+			}
+			return aw.writeLink(rel, link, fi)
+		case fi.Mode().IsRegular():
+			file, err := f.fs.Open(name)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return aw.writeFile(rel, fi, file)
+		}
+		return nil // 设备文件、管道等不打包
+	})
+	if e := aw.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// 向压缩包中写入条目，name 为使用 "/" 分隔的相对路径，目录以 "/" 结尾
+type archiveWriter interface {
+	writeDir(name string, fi os.FileInfo) error
+	writeLink(name, link string, fi os.FileInfo) error
+	writeFile(name string, fi os.FileInfo, r io.Reader) error
+	Close() error
+}
+
+type tarArchiveWriter struct {
+	tw         *tar.Writer
+	compressor io.WriteCloser // tar 外层的压缩流，可为 nil
+}
+
+func (a *tarArchiveWriter) writeHeader(name, link string, fi os.FileInfo) error {
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	return a.tw.WriteHeader(hdr)
+}
+
+func (a *tarArchiveWriter) writeDir(name string, fi os.FileInfo) error {
+	return a.writeHeader(name, "", fi)
+}
+
+func (a *tarArchiveWriter) writeLink(name, link string, fi os.FileInfo) error {
+	return a.writeHeader(name, link, fi)
+}
+
+func (a *tarArchiveWriter) writeFile(name string, fi os.FileInfo, r io.Reader) error {
+	if err := a.writeHeader(name, "", fi); err != nil {
+		return err
+	}
+	_, err := copyBuffered(a.tw, r)
+	return err
+}
+
+func (a *tarArchiveWriter) Close() error {
+	err := a.tw.Close()
+	if a.compressor != nil {
+		if e := a.compressor.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchiveWriter) create(name string, fi os.FileInfo, method uint16) (io.Writer, error) {
+	hdr, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return nil, err
+	}
+	hdr.Name = name
+	hdr.Method = method
+	return a.zw.CreateHeader(hdr)
+}
+
+func (a *zipArchiveWriter) writeDir(name string, fi os.FileInfo) error {
+	_, err := a.create(name, fi, zip.Store)
+	return err
+}
+
+// zip 中的符号链接以链接目标为内容，并在权限位中标记为链接
+func (a *zipArchiveWriter) writeLink(name, link string, fi os.FileInfo) error {
+	w, err := a.create(name, fi, zip.Store)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, link)
+	return err
+}
+
+func (a *zipArchiveWriter) writeFile(name string, fi os.FileInfo, r io.Reader) error {
+	w, err := a.create(name, fi, zip.Deflate)
+	if err != nil {
+		return err
+	}
+	_, err = copyBuffered(w, r)
+	return err
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.zw.Close()
+}
+
+// 按扩展名判断压缩包的格式，无法判断时返回 ArchiveAuto
+func archiveFormatByName(name string) ArchiveFormat {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveTar
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return ArchiveTarZst
+	}
+	return ArchiveAuto
+}
+
+// 按文件头判断压缩包的格式，无法判断时返回 ArchiveAuto
+func archiveFormatByMagic(head []byte) ArchiveFormat {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return ArchiveZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return ArchiveTarGz
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return ArchiveTarZst
+	case len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar")):
+		return ArchiveTar
+	}
+	return ArchiveAuto
+}
+
+// 将压缩包解压到 dstDir，按文件头或扩展名判断格式，支持 zip、tar、tar.gz、tar.zst
+// 解压时恢复文件和目录的权限、修改时间以及符号链接，已存在的同名文件被覆盖
+// 路径位于 dstDir 之外的条目返回 ErrUnsafeArchivePath，此前已解压的文件保留
+// Extract("./dist.tar.gz", "./deploy", nil)
+func Extract(archive, dstDir string, opts *ArchiveOptions) error {
+	return defaultFS.Extract(archive, dstDir, opts)
+}
+
+// 将压缩包解压到 dstDir
+func (f *FS) Extract(archive, dstDir string, opts *ArchiveOptions) error {
+	file, err := f.fs.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	format := archiveFormatByMagic(head[:n])
+	if format == ArchiveAuto {
+		if format = archiveFormatByName(archive); format == ArchiveAuto {
+			return fmt.Errorf("unknown archive format: %s", archive)
+		}
+	}
+	if format == ArchiveZip {
+		fi, err := f.fs.Stat(archive)
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(file, fi.Size())
+		if err != nil {
+			return err
+		}
+		return f.newExtractor(dstDir, opts).extractZip(zr)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return f.ExtractReader(bufio.NewReaderSize(file, DefaultCopyBufferSize), dstDir, format, opts)
+}
+
+// 从流中读取并解压到 dstDir，如直接解压网络下载的内容
+// 只支持 tar、tar.gz、tar.zst，zip 的目录位于文件末尾，需要随机读取，请使用 Extract
+func ExtractReader(r io.Reader, dstDir string, format ArchiveFormat, opts *ArchiveOptions) error {
+	return defaultFS.ExtractReader(r, dstDir, format, opts)
+}
+
+// 从流中读取并解压到 dstDir
+func (f *FS) ExtractReader(r io.Reader, dstDir string, format ArchiveFormat, opts *ArchiveOptions) error {
+	switch format {
+	case ArchiveTar:
+	case ArchiveTarGz:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	case ArchiveTarZst:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	case ArchiveZip:
+		return errors.New("zip archive can not be extracted from a stream, use Extract")
+	default:
+		return fmt.Errorf("unknown archive format: %s", format)
+	}
+	return f.newExtractor(dstDir, opts).extractTar(tar.NewReader(r))
+}
+
+type extractor struct {
+	fs      *FS
+	dst     string
+	filter  *Filter
+	checked map[string]bool // 已确认不是符号链接的目录
+	skipped map[string]bool // 被过滤的目录
+	through map[string]bool // 已解压的符号链接在解析时经过的目录，之后不能再被替换为链接
+	dirs    []extractedDir
+}
+
+// 解压的目录，权限和修改时间在全部条目解压后设置，避免只读目录无法写入及修改时间被改变
+type extractedDir struct {
+	path string
+	info os.FileInfo
+}
+
+func (f *FS) newExtractor(dstDir string, opts *ArchiveOptions) *extractor {
+	x := &extractor{fs: f, dst: dstDir, checked: map[string]bool{}, skipped: map[string]bool{}, through: map[string]bool{}}
+	if opts != nil {
+		x.filter = opts.Filter
+	}
+	return x
+}
+
+func (x *extractor) extractTar(tr *tar.Reader) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return x.finish()
+		}
+		if err != nil {
+			return err
+		}
+		rel, target, ok, err := x.prepare(hdr.Name, hdr.FileInfo())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(target, hdr.FileInfo())
+		case tar.TypeSymlink:
+			err = x.link(rel, target, hdr.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(target, hdr)
+		case tar.TypeReg, tar.TypeRegA:
+			err = x.file(target, hdr.FileInfo(), tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) extractZip(zr *zip.Reader) error {
+	for _, zf := range zr.File {
+		fi := zf.FileInfo()
+		rel, target, ok, err := x.prepare(zf.Name, fi)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		switch {
+		case fi.IsDir():
+			err = x.dir(target, fi)
+		case isSymlink(fi):
+			var link []byte
+			if link, err = readZipFile(zf); err == nil {
+				err = x.link(rel, target, string(link))
+			}
+		case fi.Mode().IsRegular():
+			var r io.ReadCloser
+			if r, err = zf.Open(); err == nil {
+				err = x.file(target, fi, r)
+				r.Close()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return x.finish()
+}
+
+func readZipFile(zf *zip.File) ([]byte, error) {
+	r, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// 检查条目的路径并应用过滤规则，返回相对路径和解压的目标路径，ok 为 false 时跳过该条目
+func (x *extractor) prepare(name string, fi os.FileInfo) (rel, target string, ok bool, err error) {
+	rel, err = archiveEntryPath(name)
+	if err != nil || rel == "." {
+		return "", "", false, err
+	}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if x.skipped[dir] {
+			return "", "", false, nil
+		}
+	}
+	if !x.filter.Allow(rel, fi) {
+		if fi.IsDir() {
+			x.skipped[rel] = true
+		}
+		return "", "", false, nil
+	}
+	if err := x.checkParents(rel); err != nil {
+		return "", "", false, err
+	}
+	return rel, x.fs.fs.Join(x.dst, filepath.FromSlash(rel)), true, nil
+}
+
+// 检查条目所在的各级目录都不是符号链接，避免通过压缩包中的链接写入目标目录之外
+func (x *extractor) checkParents(rel string) error {
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		if x.checked[dir] {
+			continue
+		}
+		fi, err := x.fs.fs.Lstat(x.fs.fs.Join(x.dst, filepath.FromSlash(dir)))
+		if os.IsNotExist(err) {
+			return nil // 之后的目录将由 MkdirAll 创建
+		}
+		if err != nil {
+			return err
+		}
+		if isSymlink(fi) {
+			return fmt.Errorf("%w: %s", ErrUnsafeArchivePath, rel)
+		}
+		x.checked[dir] = true
+	}
+	return nil
+}
+
+// 返回条目清理后使用 "/" 分隔的相对路径，绝对路径或包含上级目录时返回 ErrUnsafeArchivePath
+func archiveEntryPath(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, `\`, "/")
+	rel := path.Clean(slashed)
+	if path.IsAbs(slashed) || filepath.VolumeName(name) != "" || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+	return rel, nil
+}
+
+func (x *extractor) dir(target string, fi os.FileInfo) error {
+	if err := x.fs.fs.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	x.dirs = append(x.dirs, extractedDir{path: target, info: fi})
+	return nil
+}
+
+// 创建符号链接，链接目标须位于目标目录之内，且解析时不经过其他符号链接
+// 否则如 "d -> ." 之后的 "evil -> d/../victim" 按文本判断位于目录内，实际指向目标目录之外
+func (x *extractor) link(rel, target, link string) error {
+	unsafe := fmt.Errorf("%w: %s -> %s", ErrUnsafeArchivePath, rel, link)
+	if x.through[rel] || path.IsAbs(filepath.ToSlash(link)) {
+		return unsafe
+	}
+	var through []string
+	cur := path.Dir(rel)
+	parts := strings.Split(filepath.ToSlash(link), "/")
+	for i, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if cur == "." {
+				return unsafe
+			}
+			cur = path.Dir(cur)
+			continue
+		}
+		cur = path.Join(cur, part)
+		if i == len(parts)-1 {
+			break // 最后一段可以是链接，其自身已通过检查
+		}
+		fi, err := x.fs.fs.Lstat(x.fs.fs.Join(x.dst, filepath.FromSlash(cur)))
+		if err == nil && isSymlink(fi) {
+			return unsafe
+		}
+		through = append(through, cur)
+	}
+	if err := x.fs.fs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := x.fs.fs.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(x.checked, rel)
+	for _, dir := range through {
+		x.through[dir] = true
+	}
+	return x.fs.fs.Symlink(link, target)
+}
+
+// 硬链接指向此前解压的普通文件，复制其内容；不跟随符号链接读取，避免读入目标目录之外的文件
+func (x *extractor) hardlink(target string, hdr *tar.Header) error {
+	linked, err := archiveEntryPath(hdr.Linkname)
+	if err != nil {
+		return err
+	}
+	if err := x.checkParents(linked); err != nil {
+		return err
+	}
+	src := x.fs.fs.Join(x.dst, filepath.FromSlash(linked))
+	fi, err := x.fs.fs.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s => %s", ErrUnsafeArchivePath, hdr.Name, hdr.Linkname)
+	}
+	file, err := x.fs.fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return x.file(target, hdr.FileInfo(), file)
+}
+
+func (x *extractor) file(target string, fi os.FileInfo, r io.Reader) error {
+	// 已存在的同名符号链接需先删除，否则会写入链接指向的文件
+	if old, err := x.fs.fs.Lstat(target); err == nil && isSymlink(old) {
+		if err := x.fs.fs.Remove(target); err != nil {
+			return err
+		}
+	}
+	file, err := x.fs.fs.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if os.IsNotExist(err) {
+		if err = x.fs.fs.MkdirAll(filepath.Dir(target), 0755); err == nil {
+			file, err = x.fs.fs.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = copyBuffered(file, r)
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	return x.restore(target, fi)
+}
+
+// 恢复权限和修改时间，底层文件系统不支持时忽略
+func (x *extractor) restore(target string, fi os.FileInfo) error {
+	err := x.fs.preserveMetadata(target, target, fi, PreserveMode|PreserveTimes)
+	if errors.Is(err, billy.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+// 从最深的目录开始设置目录的权限和修改时间
+func (x *extractor) finish() error {
+	for i := len(x.dirs) - 1; i >= 0; i-- {
+		if err := x.restore(x.dirs[i].path, x.dirs[i].info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
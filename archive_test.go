@@ -0,0 +1,187 @@
+package zfile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestArchiveAndExtract(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	ReWriteFile(filepath.Join(src, "a.txt"), []byte("hello"))
+	ReWriteFile(filepath.Join(src, "bin", "run.sh"), []byte("#!/bin/sh"))
+	ReWriteFile(filepath.Join(src, "logs", "app.log"), []byte("log"))
+	os.MkdirAll(filepath.Join(src, "empty"), 0755)
+	os.Chmod(filepath.Join(src, "bin", "run.sh"), 0750)
+	mtime := time.Date(2021, 5, 1, 8, 30, 0, 0, time.Local)
+	// tar、zip 中的修改时间精确到秒
+	for _, name := range []string{"a.txt", "bin/run.sh", "logs/app.log"} {
+		os.Chtimes(filepath.Join(src, name), mtime, mtime)
+	}
+	opts := &ArchiveOptions{Filter: NewFilter().Exclude("*.log")}
+
+	for _, name := range []string{"out.zip", "out.tar", "out.tar.gz", "out.tar.zst"} {
+		archive := filepath.Join(dir, name)
+		assert.Nil(t, Archive(src, archive, ArchiveAuto, opts), name)
+		dst := filepath.Join(dir, "dst-"+name)
+		assert.Nil(t, Extract(archive, dst, nil), name)
+
+		diff, err := DiffDirs(src, dst, &DiffOptions{Filter: opts.Filter})
+		assert.Nil(t, err)
+		assert.True(t, diff.Empty(), "%s: %s", name, diff)
+		assert.True(t, IsDir(filepath.Join(dst, "empty")), name)
+		assert.True(t, IsDir(filepath.Join(dst, "logs")), name)
+		assert.False(t, IsFile(filepath.Join(dst, "logs", "app.log")), name)
+		fi, _ := os.Stat(filepath.Join(dst, "bin", "run.sh"))
+		assert.Equal(t, os.FileMode(0750), fi.Mode().Perm(), name)
+		fi, _ = os.Stat(filepath.Join(dst, "a.txt"))
+		assert.True(t, mtime.Equal(fi.ModTime()), name)
+	}
+
+	// 按过滤规则只解压部分内容
+	dst := filepath.Join(dir, "partial")
+	assert.Nil(t, Extract(filepath.Join(dir, "out.tar.gz"), dst, &ArchiveOptions{Filter: NewFilter().Exclude("bin/")}))
+	assert.True(t, IsFile(filepath.Join(dst, "a.txt")))
+	assert.False(t, IsDir(filepath.Join(dst, "bin")))
+
+	assert.NotNil(t, Archive(src, filepath.Join(dir, "out.rar"), ArchiveAuto, nil))
+	assert.NotNil(t, Archive(filepath.Join(dir, "none"), filepath.Join(dir, "none.zip"), ArchiveAuto, nil))
+}
+
+func TestArchiveSymlink(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	ReWriteFile(filepath.Join(src, "real.txt"), []byte("real"))
+	os.Symlink("real.txt", filepath.Join(src, "link.txt"))
+	fs := Default().WithSymlinkPolicy(SymlinkKeep)
+
+	for _, name := range []string{"links.zip", "links.tar"} {
+		archive := filepath.Join(dir, name)
+		assert.Nil(t, fs.Archive(src, archive, ArchiveAuto, nil))
+		dst := filepath.Join(dir, "dst-"+name)
+		assert.Nil(t, Extract(archive, dst, nil))
+		link, err := os.Readlink(filepath.Join(dst, "link.txt"))
+		assert.Nil(t, err, name)
+		assert.Equal(t, "real.txt", link, name)
+	}
+}
+
+func TestArchiveStream(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a.txt", []byte("a"))
+	fs.ReWriteFile("/src/sub/b.txt", []byte("b"))
+
+	var buf bytes.Buffer
+	assert.Nil(t, fs.WriteArchive(&buf, "/src", ArchiveTarZst, nil))
+	assert.Nil(t, fs.ExtractReader(&buf, "/dst", ArchiveTarZst, nil))
+	b, _ := fs.ReadFile("/dst/sub/b.txt")
+	assert.Equal(t, "b", b)
+
+	// 按文件头判断格式，与扩展名无关
+	assert.Nil(t, fs.Archive("/src", "/out.bin", ArchiveZip, nil))
+	assert.Nil(t, fs.Extract("/out.bin", "/dst2", nil))
+	b, _ = fs.ReadFile("/dst2/a.txt")
+	assert.Equal(t, "a", b)
+
+	assert.NotNil(t, fs.ExtractReader(&buf, "/dst", ArchiveZip, nil))
+}
+
+func TestExtractUnsafePath(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "dst")
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	w, _ := zw.Create("../evil.txt")
+	w.Write([]byte("evil"))
+	zw.Close()
+	ReWriteFile(filepath.Join(dir, "slip.zip"), zbuf.Bytes())
+	err := Extract(filepath.Join(dir, "slip.zip"), dst, nil)
+	assert.True(t, errors.Is(err, ErrUnsafeArchivePath))
+	assert.False(t, IsFile(filepath.Join(dir, "evil.txt")))
+
+	// 先创建指向目标目录之外的链接，再通过链接写入
+	tarWith := func(headers ...*tar.Header) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range headers {
+			tw.WriteHeader(hdr)
+		}
+		tw.Close()
+		return &buf
+	}
+	err = ExtractReader(tarWith(&tar.Header{Name: "up", Typeflag: tar.TypeSymlink, Linkname: "../.."}), dst, ArchiveTar, nil)
+	assert.True(t, errors.Is(err, ErrUnsafeArchivePath))
+	err = ExtractReader(tarWith(
+		&tar.Header{Name: "sub/", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "sub"},
+		&tar.Header{Name: "link/evil.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	), dst, ArchiveTar, nil)
+	assert.True(t, errors.Is(err, ErrUnsafeArchivePath))
+	assert.False(t, IsFile(filepath.Join(dst, "sub", "evil.txt")))
+	err = ExtractReader(tarWith(&tar.Header{Name: "/etc/evil", Typeflag: tar.TypeReg}), dst, ArchiveTar, nil)
+	assert.True(t, errors.Is(err, ErrUnsafeArchivePath))
+}
+
+func TestExtractLinkThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	victim := filepath.Join(dir, "victim.txt")
+	ReWriteFile(victim, []byte("safe"))
+	tarWith := func(headers ...*tar.Header) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range headers {
+			tw.WriteHeader(hdr)
+			if hdr.Typeflag == tar.TypeReg {
+				tw.Write([]byte("PWNED"))
+			}
+		}
+		tw.Close()
+		return &buf
+	}
+
+	// 链接目标按文本位于目录内，但经过 "d -> ." 后指向目标目录之外，再由硬链接写入
+	dst := filepath.Join(dir, "dst")
+	err := ExtractReader(tarWith(
+		&tar.Header{Name: "d", Typeflag: tar.TypeSymlink, Linkname: "."},
+		&tar.Header{Name: "evil", Typeflag: tar.TypeSymlink, Linkname: "d/../victim.txt"},
+		&tar.Header{Name: "payload", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+		&tar.Header{Name: "evil", Typeflag: tar.TypeLink, Linkname: "payload"},
+	), dst, ArchiveTar, nil)
+	assert.True(t, errors.Is(err, ErrUnsafeArchivePath))
+	s, _ := ReadFile(victim)
+	assert.Equal(t, "safe", s)
+
+	// 先创建经过 d 的链接，再将 d 替换为链接
+	dst = filepath.Join(dir, "dst2")
+	err = ExtractReader(tarWith(
+		&tar.Header{Name: "evil", Typeflag: tar.TypeSymlink, Linkname: "d/../victim.txt"},
+		&tar.Header{Name: "d", Typeflag: tar.TypeSymlink, Linkname: "."},
+	), dst, ArchiveTar, nil)
+	assert.True(t, errors.Is(err, ErrUnsafeArchivePath))
+
+	// 硬链接不能读取链接指向的文件，已存在的同名链接被替换而不是写入其指向的文件
+	dst = filepath.Join(dir, "dst3")
+	os.MkdirAll(dst, 0755)
+	os.Symlink(victim, filepath.Join(dst, "out"))
+	err = ExtractReader(tarWith(&tar.Header{Name: "steal", Typeflag: tar.TypeLink, Linkname: "out"}), dst, ArchiveTar, nil)
+	assert.True(t, errors.Is(err, ErrUnsafeArchivePath))
+	err = ExtractReader(tarWith(
+		&tar.Header{Name: "payload", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+		&tar.Header{Name: "out", Typeflag: tar.TypeLink, Linkname: "payload"},
+	), dst, ArchiveTar, nil)
+	assert.Nil(t, err)
+	s, _ = ReadFile(victim)
+	assert.Equal(t, "safe", s)
+	s, _ = ReadFile(filepath.Join(dst, "out"))
+	assert.Equal(t, "PWNED", s)
+}
@@ -0,0 +1,158 @@
+package zfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+var tempFileSeq uint64
+
+// 原子地覆盖写入文件：先写入同目录下的临时文件并 fsync，再重命名覆盖目标文件并 fsync 所在目录
+// 写入过程中崩溃时，目标文件保持原有内容；目录不存在时先创建目录
+// keepPerm 为 true 且目标文件已存在时沿用原文件的权限，否则与 ReWriteFile 创建的文件权限相同
+// ("../build/app.conf",[]byte("..."))
+func ReWriteFileAtomic(relitivePathAndFileName string, b []byte, keepPerm ...bool) error {
+	return defaultFS.ReWriteFileAtomic(relitivePathAndFileName, b, keepPerm...)
+}
+
+// 同 ReWriteFileAtomic，由 write 向临时文件写入内容，write 返回错误时目标文件不变
+func WriteAtomic(relitivePathAndFileName string, write func(w io.Writer) error, keepPerm ...bool) error {
+	return defaultFS.WriteAtomic(relitivePathAndFileName, write, keepPerm...)
+}
+
+// 原子地覆盖写入文件
+func (f *FS) ReWriteFileAtomic(relitivePathAndFileName string, b []byte, keepPerm ...bool) error {
+	return f.WriteAtomic(relitivePathAndFileName, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	}, keepPerm...)
+}
+
+// 同 ReWriteFileAtomic，由 write 向临时文件写入内容，write 返回错误时目标文件不变
+// 非本地文件系统无法 fsync，仅保证写入临时文件后再重命名
+func (f *FS) WriteAtomic(relitivePathAndFileName string, write func(w io.Writer) error, keepPerm ...bool) error {
+	keep := len(keepPerm) > 0 && keepPerm[0]
+	c, err := f.writeCompression(relitivePathAndFileName)
+	if err != nil {
+		return err
+	}
+	if c != CompressionNone {
+		plain := write
+		write = func(w io.Writer) error {
+			return writeCompressed(w, c, plain)
+		}
+	}
+	if name, ok := f.osPath(relitivePathAndFileName); ok {
+		return writeAtomicOS(name, write, keep)
+	}
+	return f.writeAtomicBilly(relitivePathAndFileName, write, keep)
+}
+
+func writeAtomicOS(name string, write func(w io.Writer) error, keepPerm bool) (err error) {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := createSiblingTemp(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if keepPerm {
+		if fi, e := os.Stat(name); e == nil {
+			if err = tmp.Chmod(fi.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// 在 name 所在目录创建临时文件，权限与 os.Create 相同（0666 去掉 umask）
+func createSiblingTemp(name string) (*os.File, error) {
+	dir, base := filepath.Split(name)
+	for i := 0; ; i++ {
+		seq := atomic.AddUint64(&tempFileSeq, 1)
+		tmpName := filepath.Join(dir, fmt.Sprintf(".%s.%d%d.tmp", base, time.Now().UnixNano(), seq))
+		file, err := os.OpenFile(tmpName, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && i < 100 {
+			continue
+		}
+		return file, err
+	}
+}
+
+// fsync 目录，使重命名持久化；windows 不支持对目录 fsync
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (f *FS) writeAtomicBilly(name string, write func(w io.Writer) error, keepPerm bool) (err error) {
+	dir, base := filepath.Split(name)
+	if dir != "" {
+		if err := f.fs.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	tmp, err := f.fs.TempFile(dir, "."+base+".")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			f.fs.Remove(tmp.Name())
+		}
+	}()
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if s, ok := tmp.(interface{ Sync() error }); ok {
+		if err = s.Sync(); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if change, ok := f.fs.(billy.Change); ok && keepPerm {
+		if fi, e := f.fs.Stat(name); e == nil {
+			if err = change.Chmod(tmp.Name(), fi.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+	return f.fs.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,46 @@
+package zfile
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestReWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "conf", "app.conf")
+	assert.Nil(t, ReWriteFileAtomic(name, []byte("v1")))
+	os.Chmod(name, 0600)
+
+	assert.Nil(t, ReWriteFileAtomic(name, []byte("v2"), true))
+	content, _ := ReadFile(name)
+	assert.Equal(t, "v2", content)
+	fi, _ := os.Stat(name)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	err := WriteAtomic(name, func(w io.Writer) error {
+		w.Write([]byte("half"))
+		return errors.New("写入失败")
+	})
+	assert.NotNil(t, err)
+	content, _ = ReadFile(name)
+	assert.Equal(t, "v2", content)
+	infos, _ := ioutil.ReadDir(filepath.Dir(name))
+	assert.Len(t, infos, 1)
+}
+
+func TestReWriteFileAtomicMemory(t *testing.T) {
+	fs := NewFS(memfs.New())
+	assert.Nil(t, fs.ReWriteFileAtomic("conf/app.conf", []byte("v1")))
+	assert.Nil(t, fs.ReWriteFileAtomic("conf/app.conf", []byte("v2")))
+	content, _ := fs.ReadFile("conf/app.conf")
+	assert.Equal(t, "v2", content)
+	files, _ := fs.GetFileListBySuffix("conf", "")
+	assert.Equal(t, []string{"conf/app.conf"}, files)
+}
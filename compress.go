@@ -0,0 +1,235 @@
+package zfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 文件的压缩格式
+type Compression string
+
+const (
+	// 读取时按文件头判断，写入时按扩展名判断
+	CompressionAuto  Compression = ""
+	CompressionNone  Compression = "none"
+	CompressionGzip  Compression = "gzip"  // .gz
+	CompressionBzip2 Compression = "bzip2" // .bz2，只支持读取
+	CompressionZstd  Compression = "zstd"  // .zst
+)
+
+// 不支持以该格式压缩，如 bzip2
+var ErrCompressionUnsupported = errors.New("compression format unsupported for writing")
+
+// 按文件头判断压缩格式，不是已知的压缩格式时返回 CompressionNone
+// 判断 gzip、zstd 至少需要 4 个字节，bzip2 需要 10 个字节
+func DetectCompression(head []byte) Compression {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b, 0x08}):
+		return CompressionGzip
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return CompressionZstd
+	case len(head) >= 10 && bytes.HasPrefix(head, []byte("BZh")) && head[3] >= '1' && head[3] <= '9':
+		// 文件头之后为数据块或流结束的标记，避免将以 "BZh" 开头的文本误判为 bzip2
+		block := head[4:10]
+		if bytes.Equal(block, []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}) || bytes.Equal(block, []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}) {
+			return CompressionBzip2
+		}
+	}
+	return CompressionNone
+}
+
+// 按扩展名判断压缩格式，不区分大小写，未知的扩展名返回 CompressionNone
+func compressionByName(name string) Compression {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(lower, ".bz2"):
+		return CompressionBzip2
+	case strings.HasSuffix(lower, ".zst"):
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// 返回解压 reader 的 io.ReadCloser 及按文件头识别出的压缩格式，不是压缩格式时原样读取
+// Close 不会关闭 reader
+func NewDecompressReader(reader io.Reader) (io.ReadCloser, Compression, error) {
+	buf := bufio.NewReader(reader)
+	head, err := buf.Peek(10)
+	if err != nil && err != io.EOF {
+		return nil, CompressionNone, err
+	}
+	c := DetectCompression(head)
+	switch c {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(buf)
+		if err != nil {
+			return nil, c, err
+		}
+		return gr, c, nil
+	case CompressionBzip2:
+		return ioutil.NopCloser(bzip2.NewReader(buf)), c, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(buf)
+		if err != nil {
+			return nil, c, err
+		}
+		return zstdReadCloser{zr}, c, nil
+	}
+	return ioutil.NopCloser(buf), c, nil
+}
+
+// 返回以格式 c 压缩后写入 writer 的 io.WriteCloser，Close 时写入剩余的压缩数据，但不会关闭 writer
+// c 为 CompressionNone 时原样写入，为 CompressionBzip2 时返回 ErrCompressionUnsupported
+func NewCompressWriter(writer io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewWriter(writer), nil
+	case CompressionZstd:
+		return zstd.NewWriter(writer)
+	case CompressionNone, CompressionAuto:
+		return nopWriteCloser{writer}, nil
+	}
+	return nil, compressionWritable(c)
+}
+
+// 判断是否支持以格式 c 压缩
+func compressionWritable(c Compression) error {
+	switch c {
+	case CompressionGzip, CompressionZstd, CompressionNone, CompressionAuto:
+		return nil
+	case CompressionBzip2:
+		return fmt.Errorf("%w: %s", ErrCompressionUnsupported, c)
+	}
+	return fmt.Errorf("unknown compression: %s", c)
+}
+
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (r zstdReadCloser) Close() error {
+	r.Decoder.Close()
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// 同时关闭压缩流和文件
+type compressedFile struct {
+	io.ReadCloser // 读取时为解压流
+	io.Writer     // 写入时为压缩流
+	stream        io.Closer
+	file          io.Closer
+}
+
+func (c *compressedFile) Close() error {
+	err := c.stream.Close()
+	if e := c.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// 打开文件用于读取，gzip、bzip2、zstd 格式的文件按文件头识别并透明解压，其他文件原样读取
+// 读取完毕后需调用 Close
+// r, err := OpenCompressed("./logs/access.log.gz")
+func OpenCompressed(name string) (io.ReadCloser, error) {
+	return defaultFS.OpenCompressed(name)
+}
+
+// 打开文件用于读取，压缩格式的文件透明解压
+func (f *FS) OpenCompressed(name string) (io.ReadCloser, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := NewDecompressReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &compressedFile{ReadCloser: r, stream: r, file: file}, nil
+}
+
+// 创建或覆盖文件用于写入，c 为 CompressionAuto 时按扩展名判断压缩格式，目录不存在时先创建目录
+// 写入完毕后需调用 Close
+// w, err := CreateCompressed("./logs/access.log.zst", CompressionAuto)
+func CreateCompressed(name string, c Compression) (io.WriteCloser, error) {
+	return defaultFS.CreateCompressed(name, c)
+}
+
+// 创建或覆盖文件用于写入，写入的内容按格式 c 压缩
+func (f *FS) CreateCompressed(name string, c Compression) (io.WriteCloser, error) {
+	if c == CompressionAuto {
+		c = compressionByName(name)
+	}
+	if err := compressionWritable(c); err != nil {
+		return nil, err
+	}
+	file, err := f.create(name)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewCompressWriter(file, c)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &compressedFile{Writer: w, stream: w, file: file}, nil
+}
+
+// 返回透明压缩和解压的 FS：ReadFile、ReadFileByte、ReadFileLines、ReadLines、OpenLineReader、ReadFileWithEncoding
+// 及 ReadJSON、ReadCSV 等结构化读取按文件头识别并解压；ReWriteFile、WriteFileWithEncoding、WriteAtomic、ReWriteFileAtomic
+// 及 WriteJSON、WriteCSV 等结构化写入按扩展名压缩；WriteAppend 每次追加一个新的 gzip 成员或 zstd 帧
+// OpenAppender 和 WriteAt 不支持压缩文件，返回 ErrCompressionUnsupported；其他函数按原始字节读写
+// Default().WithCompression(true).ReadFileLines("./logs/access.log.gz")
+func (f *FS) WithCompression(enabled bool) *FS {
+	c := *f
+	c.compression = enabled
+	return &c
+}
+
+// 打开文件用于读取，启用透明压缩时解压
+func (f *FS) open(name string) (io.ReadCloser, error) {
+	if f.compression {
+		return f.OpenCompressed(name)
+	}
+	return f.fs.Open(name)
+}
+
+// 返回写入 name 时使用的压缩格式，未启用透明压缩时为 CompressionNone
+func (f *FS) writeCompression(name string) (Compression, error) {
+	if !f.compression {
+		return CompressionNone, nil
+	}
+	c := compressionByName(name)
+	return c, compressionWritable(c)
+}
+
+// 由 write 写入内容，按格式 c 压缩后写入 w
+func writeCompressed(w io.Writer, c Compression, write func(w io.Writer) error) error {
+	cw, err := NewCompressWriter(w, c)
+	if err != nil {
+		return err
+	}
+	if err := write(cw); err != nil {
+		cw.Close()
+		return err
+	}
+	return cw.Close()
+}
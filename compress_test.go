@@ -0,0 +1,109 @@
+package zfile
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+// "line1\nline2\n" 的 bzip2 压缩结果
+const bzip2Lines = "BZh91AY&SY\x16\x05\x15\x4b\x00\x00\x04\x49\x00\x00\x10\x30\x00\x02\x25\x20\x00\x31\x0c\x00\x94\x68\x7a\x92\x60\x89\xc2\x78\xbb\x92\x29\xc2\x84\x80\xb0\x28\xaa\x58"
+
+func TestWithCompression(t *testing.T) {
+	plain := NewFS(memfs.New())
+	fs := plain.WithCompression(true)
+
+	for _, name := range []string{"/logs/access.log.gz", "/logs/access.log.zst"} {
+		assert.Nil(t, fs.ReWriteFile(name, []byte("line1\r\nline2\n")))
+		raw, _ := plain.ReadFileByte(name)
+		assert.NotEqual(t, CompressionNone, DetectCompression(raw), name)
+		lines, err := fs.ReadFileLines(name)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"line1", "line2"}, lines, name)
+	}
+
+	// 按文件头识别，与扩展名无关；不是压缩格式的文件原样读取
+	plain.ReWriteFile("/logs/old.log", []byte(bzip2Lines))
+	lines, _ := fs.ReadFileLines("/logs/old.log")
+	assert.Equal(t, []string{"line1", "line2"}, lines)
+	fs.ReWriteFile("/logs/plain.txt", []byte("BZh9 plain text"))
+	s, _ := plain.ReadFile("/logs/plain.txt")
+	assert.Equal(t, "BZh9 plain text", s)
+	s, _ = fs.ReadFile("/logs/plain.txt")
+	assert.Equal(t, "BZh9 plain text", s)
+
+	assert.Nil(t, fs.WriteFileWithEncoding("/logs/gbk.txt.gz", "中文", EncodingGBK, false))
+	s, enc, err := fs.ReadFileWithEncoding("/logs/gbk.txt.gz", EncodingAuto)
+	assert.Nil(t, err)
+	assert.Equal(t, "中文", s)
+	assert.Equal(t, EncodingGBK, enc)
+
+	err = fs.ReWriteFile("/logs/new.log.bz2", []byte("x"))
+	assert.True(t, errors.Is(err, ErrCompressionUnsupported))
+	assert.False(t, fs.IsFile("/logs/new.log.bz2"))
+}
+
+func TestCompressedAppend(t *testing.T) {
+	plain := NewFS(memfs.New())
+	fs := plain.WithCompression(true)
+
+	// 每次追加一个新的 gzip 成员或 zstd 帧，解压后按顺序拼接
+	for _, name := range []string{"/logs/access.log.gz", "/logs/access.log.zst"} {
+		assert.Nil(t, fs.WriteAppend(name, []byte("line1\n")))
+		assert.Nil(t, fs.WriteAppend(name, []byte("line2\n")))
+		lines, err := fs.ReadFileLines(name)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"line1", "line2"}, lines, name)
+	}
+	err := fs.WriteAppend("/logs/access.log.bz2", []byte("x"))
+	assert.True(t, errors.Is(err, ErrCompressionUnsupported))
+
+	_, err = fs.OpenAppender("/logs/access.log.gz", nil)
+	assert.True(t, errors.Is(err, ErrCompressionUnsupported))
+	err = fs.WriteAt("/logs/access.log.gz", []byte("x"), 0)
+	assert.True(t, errors.Is(err, ErrCompressionUnsupported))
+
+	// 未压缩的文件和未启用透明压缩时不受影响
+	assert.Nil(t, fs.WriteAppend("/logs/app.log", []byte("a")))
+	assert.Nil(t, fs.WriteAt("/logs/app.log", []byte("b"), 0))
+	s, _ := fs.ReadFile("/logs/app.log")
+	assert.Equal(t, "b", s)
+	a, err := plain.OpenAppender("/logs/raw.gz", nil)
+	assert.Nil(t, err)
+	a.Close()
+}
+
+func TestCompressedAtomicWrite(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "conf", "app.json.zst")
+	fs := Default().WithCompression(true)
+	assert.Nil(t, fs.ReWriteFileAtomic(name, []byte(`{"a":1}`)))
+
+	r, err := OpenCompressed(name)
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadAll(r)
+	assert.Nil(t, r.Close())
+	assert.Equal(t, `{"a":1}`, string(data))
+}
+
+func TestCreateCompressed(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.txt.gz")
+	w, err := CreateCompressed(name, CompressionAuto)
+	assert.Nil(t, err)
+	io.WriteString(w, "hello")
+	assert.Nil(t, w.Close())
+
+	raw, _ := ReadFileByte(name)
+	assert.Equal(t, CompressionGzip, DetectCompression(raw))
+	s, _ := Default().WithCompression(true).ReadFile(name)
+	assert.Equal(t, "hello", s)
+
+	_, err = CreateCompressed(name, CompressionBzip2)
+	assert.True(t, errors.Is(err, ErrCompressionUnsupported))
+	_, err = CreateCompressed(name, "lz4")
+	assert.NotNil(t, err)
+}
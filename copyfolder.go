@@ -0,0 +1,318 @@
+package zfile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+)
+
+// CopyFolderWithReport 的可选项，nil 表示全部使用默认值
+type CopyOptions struct {
+	// 为 true 时遇到错误继续复制其余文件，否则遇到第一个错误即停止
+	ContinueOnError bool
+	// 复制每个文件时读写缓冲区的大小，<=0 时使用 DefaultCopyBufferSize
+	BufferSize int
+	// 并发复制文件的协程数，<=1 时顺序复制
+	// 目录始终按文件名顺序依次创建，大于 1 时要求底层文件系统支持并发访问（memfs 不支持）
+	Workers int
+	// 过滤规则，被排除的目录不再进入，nil 表示复制全部文件
+	Filter *Filter
+	// 需要保留的元数据，目录的元数据在其中的文件全部复制完成后设置
+	Preserve Preserve
+}
+
+// 流式复制文件时默认的缓冲区大小
+const DefaultCopyBufferSize = 32 * 1024
+
+// 复制文件夹的结果统计
+type CopyReport struct {
+	Files    int           // 复制成功的文件数
+	Dirs     int           // 创建的目录数
+	Bytes    int64         // 复制的字节数
+	Links    int           // SymlinkKeep 策略下创建的符号链接数
+	Skipped  []string      // 跳过的源路径，如被过滤的路径、构成循环的链接、设备文件、管道等
+	Failures []CopyFailure // 复制失败的路径及原因
+}
+
+// 单个路径复制失败的原因
+type CopyFailure struct {
+	Path string
+	Err  error
+}
+
+// 复制文件夹时的汇总错误
+type CopyError struct {
+	Failures []CopyFailure
+}
+
+func (e *CopyError) Error() string {
+	first := e.Failures[0]
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("copy folder: %s: %v", first.Path, first.Err)
+	}
+	return fmt.Sprintf("copy folder: %d failures, first %s: %v", len(e.Failures), first.Path, first.Err)
+}
+
+// 返回第一个失败原因，便于 errors.Is 判断
+func (e *CopyError) Unwrap() error {
+	return e.Failures[0].Err
+}
+
+// 按源路径的层级不变copy，返回复制统计及汇总错误
+// 与 CopyFolder 不同，读取失败的文件不会在目标目录中留下空文件
+func CopyFolderWithReport(srcAbsDir string, targetAbsDir string, opts *CopyOptions) (*CopyReport, error) {
+	return defaultFS.CopyFolderWithReport(srcAbsDir, targetAbsDir, opts)
+}
+
+// 按源路径的层级不变copy，返回复制统计及汇总错误
+// 符号链接按 FS 的符号链接策略处理，默认保留链接本身
+func (f *FS) CopyFolderWithReport(srcAbsDir string, targetAbsDir string, opts *CopyOptions) (*CopyReport, error) {
+	return f.CopyFolderContext(context.Background(), srcAbsDir, targetAbsDir, opts)
+}
+
+// 同 CopyFolderWithReport，ctx 取消后不再复制新的文件并返回 ctx.Err()
+func CopyFolderContext(ctx context.Context, srcAbsDir string, targetAbsDir string, opts *CopyOptions) (*CopyReport, error) {
+	return defaultFS.CopyFolderContext(ctx, srcAbsDir, targetAbsDir, opts)
+}
+
+// 同 CopyFolderWithReport，ctx 取消后不再复制新的文件并返回 ctx.Err()
+func (f *FS) CopyFolderContext(ctx context.Context, srcAbsDir string, targetAbsDir string, opts *CopyOptions) (*CopyReport, error) {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	copyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c := &folderCopier{fs: f, opts: opts, report: &CopyReport{}, ctx: copyCtx, cancel: cancel, links: f.newLinkTracker()}
+	var rootInfo os.FileInfo
+	if opts.Preserve != 0 {
+		rootInfo, _ = f.fs.Stat(srcAbsDir)
+	}
+	if _, _, err := c.links.enter(srcAbsDir, false); err != nil {
+		c.fail(srcAbsDir, err)
+		return c.report, &CopyError{Failures: c.report.Failures}
+	}
+	if opts.Workers <= 1 {
+		c.copyDir(srcAbsDir, targetAbsDir, "", rootInfo, nil)
+	} else {
+		jobs := make(chan copyJob)
+		var wg sync.WaitGroup
+		for i := 0; i < opts.Workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range jobs {
+					c.copyJob(job)
+				}
+			}()
+		}
+		c.copyDir(srcAbsDir, targetAbsDir, "", rootInfo, jobs)
+		close(jobs)
+		wg.Wait()
+	}
+	c.preserveDirs()
+	if err := ctx.Err(); err != nil {
+		return c.report, err
+	}
+	if len(c.report.Failures) > 0 {
+		return c.report, &CopyError{Failures: c.report.Failures}
+	}
+	return c.report, nil
+}
+
+type folderCopier struct {
+	fs     *FS
+	opts   *CopyOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex // 保护 report
+	report *CopyReport
+	dirs   []copyJob // 已创建的目录，用于最后设置目录的元数据
+	links  *linkTracker
+}
+
+// 待复制的单个文件或目录
+type copyJob struct {
+	src    string
+	target string
+	info   os.FileInfo
+}
+
+// 记录失败，返回 true 表示需要停止复制
+func (c *folderCopier) fail(path string, err error) bool {
+	c.mu.Lock()
+	c.report.Failures = append(c.report.Failures, CopyFailure{Path: path, Err: err})
+	c.mu.Unlock()
+	if c.opts.ContinueOnError {
+		return false
+	}
+	c.cancel()
+	return true
+}
+
+// 按文件名顺序创建目录，文件交给 jobs 中的协程复制，jobs 为 nil 时直接复制
+// rel 为 srcDir 相对复制根目录的路径，info 为 srcDir 的文件信息，返回 true 表示需要停止复制
+func (c *folderCopier) copyDir(srcDir, targetDir, rel string, info os.FileInfo, jobs chan<- copyJob) bool {
+	if c.ctx.Err() != nil {
+		return true
+	}
+	infos, err := c.fs.readDir(srcDir)
+	if err != nil {
+		return c.fail(srcDir, err)
+	}
+	if err := c.fs.fs.MkdirAll(targetDir, os.ModePerm); err != nil {
+		return c.fail(targetDir, err)
+	}
+	c.mu.Lock()
+	c.report.Dirs++
+	c.mu.Unlock()
+
+	for _, lfi := range infos {
+		src := c.fs.fs.Join(srcDir, lfi.Name())
+		target := c.fs.fs.Join(targetDir, lfi.Name())
+		relPath := path.Join(rel, lfi.Name())
+		fi, skip, err := c.fs.resolveEntry(src, lfi)
+		switch {
+		case skip:
+			c.skip(src)
+		case err != nil:
+			if c.fail(src, err) {
+				return true
+			}
+		case !c.opts.Filter.Allow(relPath, fi):
+			c.skip(src)
+		case isSymlink(fi) && c.fs.symlinks == SymlinkKeep:
+			if err := c.copyLink(src, target); err != nil && c.fail(src, err) {
+				return true
+			}
+		case fi.IsDir():
+			real, entered, err := c.links.enter(src, isSymlink(lfi))
+			if err != nil {
+				if c.fail(src, err) {
+					return true
+				}
+				continue
+			}
+			if !entered { // 构成循环的链接
+				c.skip(src)
+				continue
+			}
+			if isSymlink(lfi) {
+				// memfs 等实现不解析路径中间的链接，从链接指向的真实路径读取
+				src = real
+			}
+			stop := c.copyDir(src, target, relPath, fi, jobs)
+			c.links.leave()
+			if stop {
+				return true
+			}
+		case fi.Mode().IsRegular() || isSymlink(fi):
+			job := copyJob{src: src, target: target, info: fi}
+			if jobs == nil {
+				if c.copyJob(job) {
+					return true
+				}
+				continue
+			}
+			select {
+			case jobs <- job:
+			case <-c.ctx.Done():
+				return true
+			}
+		default:
+			c.skip(src)
+		}
+	}
+	if c.opts.Preserve != 0 && info != nil {
+		c.dirs = append(c.dirs, copyJob{src: srcDir, target: targetDir, info: info})
+	}
+	return false
+}
+
+func (c *folderCopier) skip(src string) {
+	c.mu.Lock()
+	c.report.Skipped = append(c.report.Skipped, src)
+	c.mu.Unlock()
+}
+
+// 返回 true 表示需要停止复制
+func (c *folderCopier) copyJob(job copyJob) bool {
+	if c.ctx.Err() != nil {
+		return true
+	}
+	if err := c.copyFile(job.src, job.target, job.info.Mode()); err != nil {
+		return c.fail(job.src, err)
+	}
+	if err := c.fs.preserveMetadata(job.src, job.target, job.info, c.opts.Preserve); err != nil {
+		return c.fail(job.src, err)
+	}
+	return false
+}
+
+// 子目录先于父目录设置，避免写入子目录时改变父目录的修改时间
+func (c *folderCopier) preserveDirs() {
+	if c.ctx.Err() != nil {
+		return
+	}
+	for _, dir := range c.dirs {
+		if err := c.fs.preserveMetadata(dir.src, dir.target, dir.info, c.opts.Preserve); err != nil && c.fail(dir.src, err) {
+			return
+		}
+	}
+}
+
+func (c *folderCopier) copyLink(src, target string) error {
+	if err := c.fs.copyLink(src, target); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.report.Links++
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *folderCopier) copyFile(src, target string, mode os.FileMode) error {
+	w, err := c.fs.copyFile(src, target, mode, c.opts.BufferSize)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.report.Files++
+	c.report.Bytes += w
+	c.mu.Unlock()
+	return nil
+}
+
+// 在目标位置创建与 src 相同的符号链接，已存在的同名文件被替换
+func (f *FS) copyLink(src, target string) error {
+	link, err := f.fs.Readlink(src)
+	if err != nil {
+		return err
+	}
+	f.fs.Remove(target)
+	return f.fs.Symlink(link, target)
+}
+
+// 以流式方式复制单个文件，内存占用仅为缓冲区大小，失败时删除不完整的目标文件
+func (f *FS) copyFile(src, target string, mode os.FileMode, bufferSize int) (int64, error) {
+	srcFile, err := f.fs.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer srcFile.Close()
+	targetFile, err := f.fs.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return 0, err
+	}
+	w, err := copyBufferedSize(targetFile, srcFile, bufferSize)
+	if e := targetFile.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		f.fs.Remove(target)
+		return 0, err
+	}
+	return w, nil
+}
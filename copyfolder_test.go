@@ -0,0 +1,87 @@
+package zfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestCopyFolderWithReport(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a.txt", []byte("hello"))
+	fs.ReWriteFile("/src/sub/b.txt", []byte("china"))
+
+	report, err := fs.CopyFolderWithReport("/src", "/dst", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Files)
+	assert.Equal(t, 2, report.Dirs)
+	assert.Equal(t, int64(10), report.Bytes)
+
+	content, _ := fs.ReadFile("/dst/sub/b.txt")
+	assert.Equal(t, "china", content)
+}
+
+func TestCopyFolderWithReportPolicy(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a/x.txt", []byte("x"))
+	fs.ReWriteFile("/src/b/y.txt", []byte("y"))
+	fs.ReWriteFile("/dst/a", []byte("目标位置已存在同名文件"))
+
+	report, err := fs.CopyFolderWithReport("/src", "/dst", nil)
+	assert.NotNil(t, err)
+	assert.Len(t, report.Failures, 1)
+	assert.Equal(t, 0, report.Files)
+
+	report, err = fs.CopyFolderWithReport("/src", "/dst", &CopyOptions{ContinueOnError: true})
+	copyErr, ok := err.(*CopyError)
+	assert.True(t, ok)
+	assert.Len(t, copyErr.Failures, 1)
+	assert.Equal(t, "/dst/a", copyErr.Failures[0].Path)
+	assert.Equal(t, 1, report.Files)
+	assert.True(t, fs.IsFile("/dst/b/y.txt"))
+}
+
+func TestCopyFolderWithReportBufferSize(t *testing.T) {
+	fs := NewFS(memfs.New())
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	fs.ReWriteFile("/src/big.bin", data)
+
+	report, err := fs.CopyFolderWithReport("/src", "/dst", &CopyOptions{BufferSize: 16})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(data)), report.Bytes)
+	copied, _ := fs.ReadFileByte("/dst/big.bin")
+	assert.Equal(t, data, copied)
+}
+
+func TestCopyFolderContextWorkers(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	target := filepath.Join(t.TempDir(), "target")
+	for i := 0; i < 20; i++ {
+		ReWriteFile(filepath.Join(src, fmt.Sprintf("d%d", i%4), fmt.Sprintf("f%d.txt", i)), []byte("hello"))
+	}
+
+	report, err := CopyFolderContext(context.Background(), src, target, &CopyOptions{Workers: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, 20, report.Files)
+	assert.Equal(t, 5, report.Dirs)
+	assert.Equal(t, int64(100), report.Bytes)
+	files, _ := GetFileListBySuffix(target, ".txt")
+	assert.Len(t, files, 20)
+}
+
+func TestCopyFolderContextCanceled(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a.txt", []byte("hello"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := fs.CopyFolderContext(ctx, "/src", "/dst", nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, report.Files)
+	assert.False(t, fs.CheckFileIsExist("/dst/a.txt"))
+}
@@ -0,0 +1,448 @@
+package zfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/transform"
+)
+
+// ReadCSV、WriteCSV 等的可选项，nil 表示全部使用默认值
+type CSVOptions struct {
+	// 字段分隔符，为 0 时使用 ','
+	Comma rune
+	// 文件的字符编码，默认不转换；读取 Excel 导出的 GBK 等文件时可指定 EncodingAuto 自动识别，写入时为 EncodingAuto 则使用 UTF-8
+	Encoding Encoding
+	// 写入时在开头写入 BOM，便于 Excel 识别 UTF-8 编码
+	BOM bool
+}
+
+// 读取 CSV 文件到 out 中，out 为结构体切片的指针时第一行为表头，按表头将各列填入结构体的字段
+// 字段按 `csv:"列名"` 标签匹配，没有标签时按字段名匹配（不区分大小写），标签为 "-" 的字段忽略
+// 字段支持字符串、布尔、整数、浮点数、time.Duration 及实现了 encoding.TextUnmarshaler 的类型（如 time.Time）
+// out 为 *[][]string 时原样返回全部行，包括表头
+// var users []User; err := ReadCSV("./data/users.csv", &users, nil)
+func ReadCSV(file string, out interface{}, opts *CSVOptions) error {
+	return defaultFS.ReadCSV(file, out, opts)
+}
+
+// 将 in 写入 CSV 文件，in 为结构体切片时先写入表头，为 [][]string 时原样写入
+// 原子地覆盖写入，目录不存在时先创建目录
+// WriteCSV("./data/users.csv", users, &CSVOptions{BOM: true})
+func WriteCSV(file string, in interface{}, opts *CSVOptions) error {
+	return defaultFS.WriteCSV(file, in, opts)
+}
+
+// 逐行读取 CSV 文件，第一行为表头，之后每行回调一次 fn，由 fn 调用 decode 将该行解析到结构体指针或 *map[string]string 中
+// 不会将整个文件读入内存，fn 返回 ErrStopLines 时停止读取并返回 nil
+// ReadCSVRows("./data/users.csv", func(decode func(v interface{}) error) error { var u User; return decode(&u) }, nil)
+func ReadCSVRows(file string, fn func(decode func(v interface{}) error) error, opts *CSVOptions) error {
+	return defaultFS.ReadCSVRows(file, fn, opts)
+}
+
+// 逐行写入 CSV 文件，由 write 多次调用 encode，每次写入一行
+// encode 的参数为结构体（或其指针）时第一次调用先写入表头，为 []string 时原样写入
+// 原子地覆盖写入，目录不存在时先创建目录
+func WriteCSVRows(file string, write func(encode func(v interface{}) error) error, opts *CSVOptions) error {
+	return defaultFS.WriteCSVRows(file, write, opts)
+}
+
+// 读取 CSV 文件到 out 中
+func (f *FS) ReadCSV(file string, out interface{}, opts *CSVOptions) error {
+	slice := reflect.ValueOf(out)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ReadCSV: out must be a pointer to a slice, got %T", out)
+	}
+	slice = slice.Elem()
+	elem := slice.Type().Elem()
+	if elem == reflect.TypeOf([]string(nil)) {
+		return f.readCSV(file, opts, func(r *csv.Reader) error {
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				slice.Set(reflect.Append(slice, reflect.ValueOf(record)))
+			}
+		})
+	}
+	ptr := elem.Kind() == reflect.Ptr
+	if ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("ReadCSV: unsupported element type %s", slice.Type().Elem())
+	}
+	return f.ReadCSVRows(file, func(decode func(v interface{}) error) error {
+		v := reflect.New(elem)
+		if err := decode(v.Interface()); err != nil {
+			return err
+		}
+		if !ptr {
+			v = v.Elem()
+		}
+		slice.Set(reflect.Append(slice, v))
+		return nil
+	}, opts)
+}
+
+// 逐行读取 CSV 文件，第一行为表头
+func (f *FS) ReadCSVRows(file string, fn func(decode func(v interface{}) error) error, opts *CSVOptions) error {
+	return f.readCSV(file, opts, func(r *csv.Reader) error {
+		header, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		header = append([]string(nil), header...)
+		r.ReuseRecord = true
+		columns := map[reflect.Type][]csvField{} // 各结构体类型中每列对应的字段
+		for row := 2; ; row++ {
+			record, err := r.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = fn(func(v interface{}) error {
+				if err := decodeCSVRecord(header, record, v, columns); err != nil {
+					return fmt.Errorf("%s: row %d: %w", file, row, err)
+				}
+				return nil
+			})
+			if err != nil {
+				if err == ErrStopLines {
+					return nil
+				}
+				return err
+			}
+		}
+	})
+}
+
+// 逐行写入 CSV 文件
+func (f *FS) WriteCSVRows(file string, write func(encode func(v interface{}) error) error, opts *CSVOptions) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	enc := opts.Encoding.normalize()
+	if enc == EncodingAuto || enc == EncodingRaw {
+		enc = EncodingUTF8
+	}
+	codec, err := enc.codec()
+	if err != nil {
+		return err
+	}
+	return f.WriteAtomic(file, func(w io.Writer) error {
+		if opts.BOM {
+			if _, err := w.Write(enc.bom()); err != nil {
+				return err
+			}
+		}
+		var tw io.WriteCloser
+		if codec != nil {
+			tw = transform.NewWriter(w, codec.NewEncoder())
+			w = tw
+		}
+		cw := csv.NewWriter(w)
+		if opts.Comma != 0 {
+			cw.Comma = opts.Comma
+		}
+		var typ reflect.Type
+		var fields []csvField
+		err := write(func(v interface{}) error {
+			if record, ok := v.([]string); ok {
+				return cw.Write(record)
+			}
+			rv := reflect.Indirect(reflect.ValueOf(v))
+			if rv.Kind() != reflect.Struct {
+				return fmt.Errorf("WriteCSV: unsupported row type %T", v)
+			}
+			if typ == nil {
+				typ, fields = rv.Type(), csvFields(rv.Type())
+				header := make([]string, len(fields))
+				for i, field := range fields {
+					header[i] = field.name
+				}
+				if err := cw.Write(header); err != nil {
+					return err
+				}
+			} else if rv.Type() != typ {
+				return fmt.Errorf("WriteCSV: row type %s differs from %s", rv.Type(), typ)
+			}
+			record := make([]string, len(fields))
+			for i, field := range fields {
+				s, err := formatCSVField(rv.FieldByIndex(field.index))
+				if err != nil {
+					return fmt.Errorf("field %s: %w", field.name, err)
+				}
+				record[i] = s
+			}
+			return cw.Write(record)
+		})
+		if err != nil {
+			return err
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil || tw == nil {
+			return err
+		}
+		return tw.Close()
+	})
+}
+
+// 将 in 写入 CSV 文件
+func (f *FS) WriteCSV(file string, in interface{}, opts *CSVOptions) error {
+	slice := reflect.ValueOf(in)
+	if slice.Kind() != reflect.Slice {
+		return fmt.Errorf("WriteCSV: in must be a slice, got %T", in)
+	}
+	return f.WriteCSVRows(file, func(encode func(v interface{}) error) error {
+		for i := 0; i < slice.Len(); i++ {
+			if err := encode(slice.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, opts)
+}
+
+// 打开 CSV 文件，按编码转换后交给 read 读取
+func (f *FS) readCSV(file string, opts *CSVOptions, read func(r *csv.Reader) error) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	in, err := f.open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	text, _, err := NewTextReader(in, opts.Encoding)
+	if err != nil {
+		return err
+	}
+	// 默认不转换编码时 NewTextReader 不去掉 BOM，这里总是去掉 UTF-8 BOM，避免表头的第一列无法匹配
+	buf := bufio.NewReader(text)
+	if head, _ := buf.Peek(len(bomUTF8)); bytes.Equal(head, bomUTF8) {
+		buf.Discard(len(bomUTF8))
+	}
+	r := csv.NewReader(buf)
+	if opts.Comma != 0 {
+		r.Comma = opts.Comma
+	}
+	r.FieldsPerRecord = -1
+	return read(r)
+}
+
+// 结构体中对应 CSV 一列的字段
+type csvField struct {
+	name  string
+	index []int
+}
+
+// 返回结构体中参与 CSV 读写的字段，嵌入的结构体展开
+func csvFields(t reflect.Type) []csvField {
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("csv")
+		if tag == "-" || sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct && !isCSVText(sf.Type) {
+			for _, inner := range csvFields(sf.Type) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, csvField{name: name, index: sf.Index})
+	}
+	return fields
+}
+
+// 判断类型是否自行实现了文本的编解码，如 time.Time
+func isCSVText(t reflect.Type) bool {
+	p := reflect.PtrTo(t)
+	return p.Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) ||
+		t.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem())
+}
+
+// 按表头将一行解析到 v 中，v 为结构体指针或 *map[string]string
+func decodeCSVRecord(header, record []string, v interface{}, columns map[reflect.Type][]csvField) error {
+	if m, ok := v.(*map[string]string); ok {
+		if *m == nil {
+			*m = make(map[string]string, len(header))
+		}
+		for i, name := range header {
+			if i < len(record) {
+				(*m)[name] = record[i]
+			}
+		}
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a non-nil pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	cols, ok := columns[rv.Type()]
+	if !ok {
+		cols = matchCSVColumns(header, csvFields(rv.Type()))
+		columns[rv.Type()] = cols
+	}
+	for i, field := range cols {
+		if field.index == nil || i >= len(record) {
+			continue
+		}
+		if err := parseCSVField(rv.FieldByIndex(field.index), record[i]); err != nil {
+			return fmt.Errorf("column %s: %w", field.name, err)
+		}
+	}
+	return nil
+}
+
+// 返回表头每一列对应的字段，没有对应字段的列 index 为 nil
+func matchCSVColumns(header []string, fields []csvField) []csvField {
+	cols := make([]csvField, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		for _, field := range fields {
+			if field.name == name {
+				cols[i] = field
+				break
+			}
+			if cols[i].index == nil && strings.EqualFold(field.name, name) {
+				cols[i] = field
+			}
+		}
+	}
+	return cols
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 将字符串解析到字段中，空字符串为零值
+func parseCSVField(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(s)
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return errors.New("unsupported field type " + v.Type().String())
+	}
+	return nil
+}
+
+// 将字段格式化为字符串，nil 指针为空字符串
+func formatCSVField(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			return string(b), err
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			return time.Duration(v.Int()).String(), nil
+		}
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", errors.New("unsupported field type " + v.Type().String())
+}
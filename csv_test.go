@@ -0,0 +1,104 @@
+package zfile
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+type testAudit struct {
+	Created time.Time `csv:"created"`
+}
+
+type testUser struct {
+	testAudit
+	Name    string        `csv:"name"`
+	Age     int           `csv:"age"`
+	Score   *float64      `csv:"score"`
+	Active  bool          // 按字段名匹配
+	Timeout time.Duration `csv:"timeout"`
+	secret  string
+	Skip    string `csv:"-"`
+}
+
+func TestCSV(t *testing.T) {
+	fs := NewFS(memfs.New())
+	score := 9.5
+	created := time.Date(2021, 5, 1, 8, 0, 0, 0, time.UTC)
+	users := []testUser{
+		{testAudit{created}, "张三", 30, &score, true, time.Second, "x", "y"},
+		{Name: "li, si", Age: 20},
+	}
+	assert.Nil(t, fs.WriteCSV("/data/users.csv", users, nil))
+	s, _ := fs.ReadFile("/data/users.csv")
+	assert.Equal(t, "created,name,age,score,Active,timeout\n"+
+		"2021-05-01T08:00:00Z,张三,30,9.5,true,1s\n"+
+		"0001-01-01T00:00:00Z,\"li, si\",20,,false,0s\n", s)
+
+	var got []testUser
+	assert.Nil(t, fs.ReadCSV("/data/users.csv", &got, nil))
+	users[0].secret, users[0].Skip = "", ""
+	assert.Equal(t, users, got)
+
+	var rows [][]string
+	assert.Nil(t, fs.ReadCSV("/data/users.csv", &rows, nil))
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, "name", rows[0][1])
+
+	// 表头的顺序、大小写与字段不同，多余的列忽略
+	fs.ReWriteFile("/data/other.csv", []byte("EXTRA,Age,NAME\nx,41,王五\n"))
+	var ptrs []*testUser
+	assert.Nil(t, fs.ReadCSV("/data/other.csv", &ptrs, nil))
+	assert.Equal(t, "王五", ptrs[0].Name)
+	assert.Equal(t, 41, ptrs[0].Age)
+
+	fs.ReWriteFile("/data/bad.csv", []byte("name,age\na,1\nb,x\n"))
+	err := fs.ReadCSV("/data/bad.csv", &got, nil)
+	assert.Contains(t, err.Error(), "row 3: column age")
+	assert.NotNil(t, fs.ReadCSV("/data/bad.csv", got, nil))
+}
+
+func TestCSVRows(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out", "rows.csv")
+	opts := &CSVOptions{Comma: ';', Encoding: EncodingGBK}
+	err := WriteCSVRows(name, func(encode func(v interface{}) error) error {
+		if err := encode([]string{"名称", "数量"}); err != nil {
+			return err
+		}
+		return encode([]string{"苹果", "3"})
+	}, opts)
+	assert.Nil(t, err)
+	_, enc, _ := ReadFileWithEncoding(name, EncodingAuto)
+	assert.Equal(t, EncodingGBK, enc)
+
+	// 指定 EncodingAuto 时自动识别 GBK 编码
+	var rows []map[string]string
+	err = ReadCSVRows(name, func(decode func(v interface{}) error) error {
+		var row map[string]string
+		if err := decode(&row); err != nil {
+			return err
+		}
+		rows = append(rows, row)
+		return nil
+	}, &CSVOptions{Comma: ';', Encoding: EncodingAuto})
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{"名称": "苹果", "数量": "3"}}, rows)
+
+	// 写入 BOM 便于 Excel 识别
+	assert.Nil(t, WriteCSV(name, [][]string{{"a"}}, &CSVOptions{BOM: true}))
+	b, _ := ReadFileByte(name)
+	assert.Equal(t, "\xEF\xBB\xBFa\n", string(b))
+
+	// 读取时去掉 BOM，表头的第一列仍能匹配
+	type user struct {
+		Name string `csv:"name"`
+		Age  int    `csv:"age"`
+	}
+	assert.Nil(t, WriteCSV(name, []user{{"tom", 1}}, &CSVOptions{BOM: true}))
+	var users []user
+	assert.Nil(t, ReadCSV(name, &users, nil))
+	assert.Equal(t, []user{{"tom", 1}}, users)
+}
@@ -0,0 +1,248 @@
+package zfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 目录比较结果中一项差异的类型
+type DiffKind int
+
+const (
+	DiffAdded       DiffKind = iota // 只在新目录中存在
+	DiffRemoved                     // 只在旧目录中存在
+	DiffModified                    // 文件内容（或大小、修改时间）不同，链接目标不同
+	DiffTypeChanged                 // 类型不同，如文件变为目录
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffModified:
+		return "modified"
+	case DiffTypeChanged:
+		return "type changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// 报告中表示差异类型的符号
+func (k DiffKind) symbol() string {
+	switch k {
+	case DiffAdded:
+		return "+"
+	case DiffRemoved:
+		return "-"
+	case DiffModified:
+		return "M"
+	}
+	return "T"
+}
+
+// DiffDirs 的可选项，nil 表示按大小和修改时间比较全部文件
+type DiffOptions struct {
+	// 为 true 时按内容摘要比较大小相同的文件，否则比较大小和修改时间
+	Checksum bool
+	// 比较内容时使用的摘要算法，为空时使用 HashSHA256
+	Algorithm HashAlgorithm
+	// 过滤规则，按相对路径匹配，被排除的文件不参与比较
+	Filter *Filter
+}
+
+// 一项差异，Path 为相对比较根目录的路径，Old、New 为两侧的文件信息，不存在的一侧为 nil
+// 新增、删除或类型改变的目录只报告目录本身，不再报告其中的内容
+type DiffEntry struct {
+	Kind DiffKind
+	Path string
+	Old  os.FileInfo
+	New  os.FileInfo
+}
+
+func (e DiffEntry) String() string {
+	return e.Kind.symbol() + " " + e.Path
+}
+
+// 两个目录的比较结果
+type DirDiff struct {
+	Entries []DiffEntry // 按路径排序
+}
+
+// 两个目录是否相同
+func (d *DirDiff) Empty() bool {
+	return len(d.Entries) == 0
+}
+
+// 指定类型的差异数
+func (d *DirDiff) Count(kind DiffKind) int {
+	n := 0
+	for _, e := range d.Entries {
+		if e.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// 可读的比较报告，每行一项差异，"+" 新增、"-" 删除、"M" 修改、"T" 类型改变，最后一行为统计
+func (d *DirDiff) String() string {
+	var b strings.Builder
+	for _, e := range d.Entries {
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "%d added, %d removed, %d modified, %d type changed",
+		d.Count(DiffAdded), d.Count(DiffRemoved), d.Count(DiffModified), d.Count(DiffTypeChanged))
+	return b.String()
+}
+
+// 比较两个目录，返回 newDir 相对 oldDir 的差异
+// diff, err := DiffDirs("./src", "./backup", &DiffOptions{Checksum: true})
+// fmt.Println(diff)
+func DiffDirs(oldDir, newDir string, opts *DiffOptions) (*DirDiff, error) {
+	return defaultFS.DiffDirs(oldDir, newDir, opts)
+}
+
+// 比较两个目录，返回 newDir 相对 oldDir 的差异
+func (f *FS) DiffDirs(oldDir, newDir string, opts *DiffOptions) (*DirDiff, error) {
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	if _, err := opts.Algorithm.new(); err != nil {
+		return nil, err
+	}
+	oldTree, err := f.snapshot(oldDir, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := f.snapshot(newDir, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(oldTree)+len(newTree))
+	for rel := range oldTree {
+		paths = append(paths, rel)
+	}
+	for rel := range newTree {
+		if _, ok := oldTree[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return filepath.ToSlash(paths[i]) < filepath.ToSlash(paths[j]) })
+
+	diff := &DirDiff{}
+	reported := map[string]bool{} // 已整体报告的目录
+	for _, rel := range paths {
+		if reportedAncestor(reported, rel) {
+			continue
+		}
+		oldInfo, newInfo := oldTree[rel], newTree[rel]
+		entry := DiffEntry{Path: rel, Old: oldInfo, New: newInfo}
+		switch {
+		case newInfo == nil:
+			entry.Kind = DiffRemoved
+		case oldInfo == nil:
+			entry.Kind = DiffAdded
+		case fileType(oldInfo) != fileType(newInfo):
+			entry.Kind = DiffTypeChanged
+		default:
+			same, err := f.sameEntry(f.fs.Join(oldDir, rel), f.fs.Join(newDir, rel), oldInfo, newInfo, opts)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+			entry.Kind = DiffModified
+		}
+		if entry.Kind != DiffModified {
+			reported[rel] = true
+		}
+		diff.Entries = append(diff.Entries, entry)
+	}
+	return diff, nil
+}
+
+// 遍历目录，返回相对路径到文件信息的映射，不包含根目录
+func (f *FS) snapshot(dir string, filter *Filter) (map[string]os.FileInfo, error) {
+	if !f.IsDir(dir) {
+		return nil, fmt.Errorf("given path does not exist: %s", dir)
+	}
+	tree := make(map[string]os.FileInfo)
+	err := f.Walk(dir, func(name string, fi os.FileInfo, err error) error {
+		if errors.Is(err, ErrSymlinkLoop) { // 忽略构成循环的链接
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !filter.Allow(filepath.ToSlash(rel), fi) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		tree[rel] = fi
+		return nil
+	})
+	return tree, err
+}
+
+func reportedAncestor(reported map[string]bool, rel string) bool {
+	for dir := filepath.Dir(rel); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if reported[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// 文件类型：目录、符号链接、普通文件或其他
+func fileType(fi os.FileInfo) os.FileMode {
+	if fi.Mode().IsRegular() {
+		return 0
+	}
+	return fi.Mode().Type()
+}
+
+// 判断类型相同的两项是否相同，目录总是相同
+func (f *FS) sameEntry(oldPath, newPath string, oldInfo, newInfo os.FileInfo, opts *DiffOptions) (bool, error) {
+	switch {
+	case oldInfo.IsDir():
+		return true, nil
+	case isSymlink(oldInfo):
+		oldLink, err := f.fs.Readlink(oldPath)
+		if err != nil {
+			return false, err
+		}
+		newLink, err := f.fs.Readlink(newPath)
+		if err != nil {
+			return false, err
+		}
+		return oldLink == newLink, nil
+	case oldInfo.Size() != newInfo.Size():
+		return false, nil
+	case !opts.Checksum:
+		return oldInfo.ModTime().Equal(newInfo.ModTime()), nil
+	}
+	oldSum, err := f.hashFile(oldPath, opts.Algorithm)
+	if err != nil {
+		return false, err
+	}
+	newSum, err := f.hashFile(newPath, opts.Algorithm)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(oldSum, newSum), nil
+}
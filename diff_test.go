@@ -0,0 +1,65 @@
+package zfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestDiffDirs(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/old/same.txt", []byte("same"))
+	fs.ReWriteFile("/old/changed.txt", []byte("aaa"))
+	fs.ReWriteFile("/old/removed/a.txt", []byte("a"))
+	fs.ReWriteFile("/old/type", []byte("file"))
+	fs.ReWriteFile("/old/log/app.log", []byte("x"))
+	fs.ReWriteFile("/new/same.txt", []byte("same"))
+	fs.ReWriteFile("/new/changed.txt", []byte("bbb"))
+	fs.ReWriteFile("/new/added/b/c.txt", []byte("c"))
+	fs.ReWriteFile("/new/type/d.txt", []byte("d"))
+	fs.ReWriteFile("/new/log/app.log", []byte("y"))
+
+	diff, err := fs.DiffDirs("/old", "/new", &DiffOptions{Checksum: true, Filter: NewFilter().Exclude("*.log")})
+	assert.Nil(t, err)
+	assert.Equal(t, "+ added\n"+
+		"M changed.txt\n"+
+		"- removed\n"+
+		"T type\n"+
+		"1 added, 1 removed, 1 modified, 1 type changed", diff.String())
+	assert.False(t, diff.Empty())
+	assert.Nil(t, diff.Entries[0].Old)
+	assert.True(t, diff.Entries[0].New.IsDir())
+
+	diff, _ = fs.DiffDirs("/old", "/old", &DiffOptions{Checksum: true})
+	assert.True(t, diff.Empty())
+	assert.Equal(t, "0 added, 0 removed, 0 modified, 0 type changed", diff.String())
+}
+
+func TestDiffDirsByModTime(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	ReWriteFile(filepath.Join(src, "a.txt"), []byte("a"))
+	ReWriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"))
+
+	_, err := CopyFolderWithReport(src, dst, &CopyOptions{Preserve: PreserveTimes})
+	assert.Nil(t, err)
+	diff, err := DiffDirs(src, dst, nil)
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+
+	// 内容相同但修改时间不同，只有按内容比较时才视为相同
+	old := time.Date(2020, 3, 20, 8, 0, 0, 0, time.Local)
+	os.Chtimes(filepath.Join(dst, "sub", "b.txt"), old, old)
+	diff, _ = DiffDirs(src, dst, nil)
+	assert.Equal(t, 1, diff.Count(DiffModified))
+	assert.Equal(t, filepath.Join("sub", "b.txt"), diff.Entries[0].Path)
+	diff, _ = DiffDirs(src, dst, &DiffOptions{Checksum: true, Algorithm: HashXXHash})
+	assert.True(t, diff.Empty())
+
+	_, err = DiffDirs(src, filepath.Join(dir, "none"), nil)
+	assert.NotNil(t, err)
+}
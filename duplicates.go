@@ -0,0 +1,340 @@
+package zfile
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// 按文件开头计算部分摘要时读取的字节数
+const partialHashSize = 4 * 1024
+
+// FindDuplicatesWithOptions 的可选项，nil 表示全部使用默认值
+type DuplicateOptions struct {
+	// 忽略小于该字节数的文件，<=0 时只忽略空文件
+	MinSize int64
+	// 比较完整内容时使用的摘要算法，为空时使用 HashSHA256
+	Algorithm HashAlgorithm
+	// 过滤规则，按相对各目录的路径匹配
+	Filter *Filter
+}
+
+// 一组内容相同的文件
+type DuplicateGroup struct {
+	Size  int64
+	Hash  string   // 完整内容的摘要
+	Paths []string // 按路径排序
+	files []dupFile
+}
+
+// 删除重复文件后可释放的字节数，同一文件的多个硬链接只计算一次
+func (g DuplicateGroup) Reclaimable() int64 {
+	return g.Size * int64(distinctFiles(g.files)-1)
+}
+
+// FindDuplicates 的结果
+type DuplicateReport struct {
+	Groups      []DuplicateGroup // 按可释放字节数从大到小排列
+	Reclaimable int64            // 可释放的总字节数
+}
+
+// 可释放的总大小，如 "1.5GB"
+func (r *DuplicateReport) ReclaimableHuman() string {
+	return HumaneFileSize(uint64(r.Reclaimable))
+}
+
+type dupFile struct {
+	path string
+	info os.FileInfo
+	key  fileKey
+}
+
+// 文件的设备号和 inode，相同时为同一文件的硬链接；ok 为 false 表示平台不提供，改用 os.SameFile 比较
+type fileKey struct {
+	dev, ino uint64
+	ok       bool
+}
+
+// 不同文件的集合，同一文件的硬链接只记录一次
+type fileSet struct {
+	keys   map[fileKey]bool
+	others []os.FileInfo // 没有 fileKey 的文件
+}
+
+func (s *fileSet) contains(file dupFile) bool {
+	if file.key.ok {
+		return s.keys[file.key]
+	}
+	for _, fi := range s.others {
+		if os.SameFile(fi, file.info) {
+			return true
+		}
+	}
+	return false
+}
+
+// 加入文件，已有同一文件时返回 false
+func (s *fileSet) add(file dupFile) bool {
+	if s.contains(file) {
+		return false
+	}
+	if !file.key.ok {
+		s.others = append(s.others, file.info)
+		return true
+	}
+	if s.keys == nil {
+		s.keys = make(map[fileKey]bool)
+	}
+	s.keys[file.key] = true
+	return true
+}
+
+// 在一个或多个目录中查找内容相同的文件
+// 先按大小分组，再比较文件开头的摘要，最后比较完整内容的摘要，只读取可能重复的文件
+// report, err := FindDuplicates("./assets", "./static")
+// fmt.Println(len(report.Groups), report.ReclaimableHuman())
+func FindDuplicates(dirs ...string) (*DuplicateReport, error) {
+	return defaultFS.FindDuplicatesWithOptions(nil, dirs...)
+}
+
+// 同 FindDuplicates，可指定最小文件大小、摘要算法和过滤规则
+func FindDuplicatesWithOptions(opts *DuplicateOptions, dirs ...string) (*DuplicateReport, error) {
+	return defaultFS.FindDuplicatesWithOptions(opts, dirs...)
+}
+
+// 在一个或多个目录中查找内容相同的文件
+func (f *FS) FindDuplicates(dirs ...string) (*DuplicateReport, error) {
+	return f.FindDuplicatesWithOptions(nil, dirs...)
+}
+
+// 同 FindDuplicates，可指定最小文件大小、摘要算法和过滤规则
+func (f *FS) FindDuplicatesWithOptions(opts *DuplicateOptions, dirs ...string) (*DuplicateReport, error) {
+	if opts == nil {
+		opts = &DuplicateOptions{}
+	}
+	if _, err := opts.Algorithm.new(); err != nil {
+		return nil, err
+	}
+	bySize, err := f.collectBySize(opts, dirs)
+	if err != nil {
+		return nil, err
+	}
+	report := &DuplicateReport{}
+	for size, files := range bySize {
+		if distinctFiles(files) < 2 {
+			continue
+		}
+		partial, err := f.groupByHash(files, func(name string) ([]byte, error) {
+			return f.hashPrefix(name, partialHashSize)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, candidates := range partial {
+			full, err := f.groupByHash(candidates, func(name string) ([]byte, error) {
+				return f.hashFile(name, opts.Algorithm)
+			})
+			if err != nil {
+				return nil, err
+			}
+			for sum, same := range full {
+				group := DuplicateGroup{Size: size, Hash: sum, files: same}
+				sort.Slice(group.files, func(i, j int) bool { return group.files[i].path < group.files[j].path })
+				for _, file := range group.files {
+					group.Paths = append(group.Paths, file.path)
+				}
+				report.Groups = append(report.Groups, group)
+				report.Reclaimable += group.Reclaimable()
+			}
+		}
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Reclaimable() != b.Reclaimable() {
+			return a.Reclaimable() > b.Reclaimable()
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+	return report, nil
+}
+
+// 遍历目录，按大小对文件分组，同一路径只记录一次
+func (f *FS) collectBySize(opts *DuplicateOptions, dirs []string) (map[int64][]dupFile, error) {
+	minSize := opts.MinSize
+	if minSize <= 0 {
+		minSize = 1
+	}
+	bySize := make(map[int64][]dupFile)
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if !f.IsDir(dir) {
+			return nil, fmt.Errorf("given path does not exist: %s", dir)
+		}
+		err := f.Walk(dir, func(name string, fi os.FileInfo, err error) error {
+			if errors.Is(err, ErrSymlinkLoop) { // 忽略构成循环的链接
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, name)
+			if err != nil {
+				return err
+			}
+			if rel != "." && !opts.Filter.Allow(filepath.ToSlash(rel), fi) {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !fi.Mode().IsRegular() || fi.Size() < minSize || seen[filepath.Clean(name)] {
+				return nil
+			}
+			seen[filepath.Clean(name)] = true
+			bySize[fi.Size()] = append(bySize[fi.Size()], dupFile{path: name, info: fi, key: newFileKey(fi)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bySize, nil
+}
+
+// 按摘要分组，只返回包含至少两个不同文件的组
+func (f *FS) groupByHash(files []dupFile, sum func(name string) ([]byte, error)) (map[string][]dupFile, error) {
+	groups := make(map[string][]dupFile)
+	for _, file := range files {
+		s, err := sum(file.path)
+		if err != nil {
+			return nil, err
+		}
+		key := hex.EncodeToString(s)
+		groups[key] = append(groups[key], file)
+	}
+	for key, group := range groups {
+		if distinctFiles(group) < 2 {
+			delete(groups, key)
+		}
+	}
+	return groups, nil
+}
+
+// 计算文件开头 n 个字节的 xxHash 摘要
+func (f *FS) hashPrefix(name string, n int64) ([]byte, error) {
+	h, _ := HashXXHash.new()
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := io.CopyN(h, file, n); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// 统计不同文件的个数，同一文件的硬链接或指向同一文件的符号链接只计算一次
+func distinctFiles(files []dupFile) int {
+	var set fileSet
+	n := 0
+	for _, file := range files {
+		if set.add(file) {
+			n++
+		}
+	}
+	return n
+}
+
+// 将每组重复文件中除第一个以外的文件替换为指向第一个文件的硬链接，返回释放的字节数
+// 替换后的文件与第一个文件共享内容、权限和修改时间；文件在查找之后被修改过时不会替换，组中的符号链接保持不变
+// 只支持本地文件系统，且文件需位于同一分区
+func HardlinkDuplicates(report *DuplicateReport) (int64, error) {
+	return defaultFS.HardlinkDuplicates(report)
+}
+
+// 将每组重复文件中除第一个以外的文件替换为指向第一个文件的硬链接，返回释放的字节数
+func (f *FS) HardlinkDuplicates(report *DuplicateReport) (int64, error) {
+	var saved int64
+	for _, group := range report.Groups {
+		if len(group.files) == 0 {
+			return saved, errors.New("empty duplicate group")
+		}
+		// 只处理普通文件，跟随链接查找时组中的符号链接保持不变，也不会作为硬链接的来源
+		var files []dupFile
+		for _, file := range group.files {
+			name, ok := f.osPath(file.path)
+			if !ok {
+				return saved, billy.ErrNotSupported
+			}
+			fi, err := os.Lstat(name)
+			if err != nil {
+				return saved, err
+			}
+			if fi.Mode().IsRegular() {
+				files = append(files, file)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		keep := files[0]
+		src, _ := f.osPath(keep.path)
+		var linked fileSet
+		linked.add(keep)
+		for _, dup := range files[1:] {
+			if linked.contains(dup) { // 已经是同一文件
+				continue
+			}
+			dst, _ := f.osPath(dup.path)
+			if changed, err := fileChanged(dst, dup.info); err != nil || changed {
+				if err != nil {
+					return saved, err
+				}
+				continue
+			}
+			if err := replaceWithLink(src, dst); err != nil {
+				return saved, err
+			}
+			linked.add(dup)
+			saved += group.Size
+		}
+	}
+	return saved, nil
+}
+
+// 判断文件在 info 之后是否被修改或替换，被替换为符号链接时同样视为已修改
+func fileChanged(name string, info os.FileInfo) (bool, error) {
+	current, err := os.Lstat(name)
+	if err != nil {
+		return false, err
+	}
+	return !current.Mode().IsRegular() || !os.SameFile(current, info) || current.Size() != info.Size() || !current.ModTime().Equal(info.ModTime()), nil
+}
+
+// 先在同一目录创建临时硬链接，再重命名覆盖 dst，失败时 dst 保持不变
+func replaceWithLink(src, dst string) error {
+	dir, base := filepath.Split(dst)
+	for i := 0; ; i++ {
+		tmp := filepath.Join(dir, fmt.Sprintf(".%s.%d.link", base, time.Now().UnixNano()))
+		err := os.Link(src, tmp)
+		if os.IsExist(err) && i < 100 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, dst); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	}
+}
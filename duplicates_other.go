@@ -0,0 +1,13 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package zfile
+
+import (
+	"os"
+)
+
+// 取不到设备号和 inode 的平台按 os.SameFile 比较
+func newFileKey(fi os.FileInfo) fileKey {
+	return fileKey{}
+}
@@ -0,0 +1,77 @@
+package zfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	big := bytes.Repeat([]byte("0123456789"), 1000)
+	other := append(append([]byte(nil), big[:len(big)-1]...), 'x') // 开头相同，结尾不同
+	path := func(name string) string { return filepath.Join(dir, name) }
+	ReWriteFile(path("a/a.bin"), big)
+	ReWriteFile(path("b/b.bin"), big)
+	ReWriteFile(path("b/c.bin"), other)
+	os.Link(path("a/a.bin"), path("a/d.bin"))
+	ReWriteFile(path("a/e1.txt"), []byte("x"))
+	ReWriteFile(path("b/e2.txt"), []byte("x"))
+	ReWriteFile(path("a/empty1"), nil)
+	ReWriteFile(path("b/empty2"), nil)
+
+	report, err := FindDuplicates(path("a"), path("b"), path("a"))
+	assert.Nil(t, err)
+	assert.Len(t, report.Groups, 2)
+	assert.Equal(t, []string{path("a/a.bin"), path("a/d.bin"), path("b/b.bin")}, report.Groups[0].Paths)
+	assert.Equal(t, int64(10000), report.Groups[0].Reclaimable())
+	assert.Equal(t, []string{path("a/e1.txt"), path("b/e2.txt")}, report.Groups[1].Paths)
+	assert.Equal(t, int64(10001), report.Reclaimable)
+	assert.Equal(t, "9.8KB", report.ReclaimableHuman())
+
+	report, _ = FindDuplicatesWithOptions(&DuplicateOptions{MinSize: 2, Algorithm: HashXXHash, Filter: NewFilter().Exclude("d.bin")}, dir)
+	assert.Len(t, report.Groups, 1)
+	assert.Equal(t, []string{path("a/a.bin"), path("b/b.bin")}, report.Groups[0].Paths)
+	assert.Len(t, report.Groups[0].Hash, 16)
+
+	report, _ = FindDuplicates(dir)
+	saved, err := HardlinkDuplicates(report)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10001), saved)
+	content, _ := ReadFileByte(path("b/b.bin"))
+	assert.Equal(t, big, content)
+	report, _ = FindDuplicates(dir)
+	assert.Len(t, report.Groups, 0)
+	assert.Equal(t, "0B", report.ReclaimableHuman())
+
+	_, err = FindDuplicates(path("none"))
+	assert.NotNil(t, err)
+}
+
+func TestHardlinkDuplicatesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	ReWriteFile(path("b.bin"), []byte("same"))
+	ReWriteFile(path("c.bin"), []byte("same"))
+	os.Symlink(path("c.bin"), path("a.link")) // 排序在最前，不能作为硬链接的来源
+
+	fs := Default().WithSymlinkPolicy(SymlinkFollow)
+	report, err := fs.FindDuplicates(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{path("a.link"), path("b.bin"), path("c.bin")}, report.Groups[0].Paths)
+	saved, err := fs.HardlinkDuplicates(report)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), saved)
+	fi, _ := os.Lstat(path("a.link"))
+	assert.True(t, isSymlink(fi))
+	b, _ := os.Lstat(path("b.bin"))
+	c, _ := os.Lstat(path("c.bin"))
+	assert.True(t, b.Mode().IsRegular())
+	assert.True(t, os.SameFile(b, c))
+
+	_, err = HardlinkDuplicates(&DuplicateReport{Groups: []DuplicateGroup{{Size: 4}}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,329 @@
+package zfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// 文本文件的字符编码，名称不区分大小写，"utf8"、"utf-8" 均可
+type Encoding string
+
+const (
+	// 默认值，不做任何转换，按原始字节读取，也不去掉 BOM；写入时按 UTF-8 处理
+	EncodingRaw Encoding = ""
+	// 自动识别编码：优先按 BOM 识别，其次按内容识别，无法识别时按 UTF-8 处理
+	EncodingAuto    Encoding = "auto"
+	EncodingUTF8    Encoding = "UTF-8"
+	EncodingUTF16LE Encoding = "UTF-16LE"
+	EncodingUTF16BE Encoding = "UTF-16BE"
+	EncodingGBK     Encoding = "GBK"
+	EncodingGB18030 Encoding = "GB18030"
+)
+
+// 自动识别编码时最多检查的字节数
+const detectEncodingSize = 4096
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// 统一编码名称的写法
+func (e Encoding) normalize() Encoding {
+	name := strings.NewReplacer("-", "", "_", "").Replace(strings.ToUpper(string(e)))
+	switch name {
+	case "":
+		return EncodingRaw
+	case "AUTO":
+		return EncodingAuto
+	case "UTF8":
+		return EncodingUTF8
+	case "UTF16LE":
+		return EncodingUTF16LE
+	case "UTF16BE":
+		return EncodingUTF16BE
+	case "GBK", "CP936":
+		return EncodingGBK
+	case "GB18030":
+		return EncodingGB18030
+	}
+	return e
+}
+
+// 返回编码的转换器，UTF-8 和 EncodingRaw 返回 nil，表示无需转换
+func (e Encoding) codec() (encoding.Encoding, error) {
+	switch e.normalize() {
+	case EncodingRaw, EncodingUTF8:
+		return nil, nil
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	case EncodingGBK:
+		return simplifiedchinese.GBK, nil
+	case EncodingGB18030:
+		return simplifiedchinese.GB18030, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding: %s", string(e))
+}
+
+// 编码对应的 BOM，GBK 和 GB18030 没有 BOM
+func (e Encoding) bom() []byte {
+	switch e.normalize() {
+	case EncodingUTF8:
+		return bomUTF8
+	case EncodingUTF16LE:
+		return bomUTF16LE
+	case EncodingUTF16BE:
+		return bomUTF16BE
+	}
+	return nil
+}
+
+// 识别文本的编码，只检查前 4KB，无法识别时返回 EncodingUTF8
+// 支持 UTF-8（含 BOM）、UTF-16（含 BOM 或以英文为主）、GBK、GB18030
+func DetectEncoding(data []byte) Encoding {
+	truncated := len(data) > detectEncodingSize
+	if truncated {
+		data = data[:detectEncodingSize]
+	}
+	enc, _ := detectEncoding(data, truncated)
+	return enc
+}
+
+// 识别文本的编码，同时返回 BOM 的长度
+// truncated 为 true 表示 data 只是文本的开头，末尾可能是不完整的字符
+func detectEncoding(data []byte, truncated bool) (Encoding, int) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return EncodingUTF8, len(bomUTF8)
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return EncodingUTF16LE, len(bomUTF16LE)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return EncodingUTF16BE, len(bomUTF16BE)
+	}
+	if enc, ok := detectUTF16(data); ok {
+		return enc, 0
+	}
+	if validUTF8(data, truncated) {
+		return EncodingUTF8, 0
+	}
+	if enc, ok := detectGB(data, truncated); ok {
+		return enc, 0
+	}
+	return EncodingUTF8, 0
+}
+
+// 没有 BOM 的 UTF-16 文本中 ASCII 字符的高字节为 0，按 0 字节出现在奇数还是偶数位置判断字节序
+func detectUTF16(data []byte) (Encoding, bool) {
+	if len(data) < 2 {
+		return "", false
+	}
+	var even, odd int
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+	units := len(data) / 2
+	switch {
+	case odd*10 > units*3 && even*10 < units:
+		return EncodingUTF16LE, true
+	case even*10 > units*3 && odd*10 < units:
+		return EncodingUTF16BE, true
+	}
+	return "", false
+}
+
+func validUTF8(data []byte, truncated bool) bool {
+	if truncated {
+		// 去掉末尾被截断的字符
+		for i := 0; i < utf8.UTFMax && i < len(data); i++ {
+			if utf8.RuneStart(data[len(data)-1-i]) {
+				if !utf8.FullRune(data[len(data)-1-i:]) {
+					data = data[:len(data)-1-i]
+				}
+				break
+			}
+		}
+	}
+	return utf8.Valid(data)
+}
+
+// 按 GB18030 的字节结构检查文本，只出现双字节字符时视为 GBK
+func detectGB(data []byte, truncated bool) (Encoding, bool) {
+	enc := EncodingGBK
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b < 0x80:
+			i++
+			continue
+		case b == 0x80 || b == 0xFF:
+			return "", false
+		}
+		if i+1 >= len(data) {
+			return enc, truncated
+		}
+		b2 := data[i+1]
+		switch {
+		case b2 >= 0x40 && b2 <= 0xFE && b2 != 0x7F:
+			i += 2
+		case b2 >= 0x30 && b2 <= 0x39:
+			if i+3 >= len(data) {
+				return EncodingGB18030, truncated
+			}
+			if data[i+2] < 0x81 || data[i+2] > 0xFE || data[i+3] < 0x30 || data[i+3] > 0x39 {
+				return "", false
+			}
+			enc = EncodingGB18030
+			i += 4
+		default:
+			return "", false
+		}
+	}
+	return enc, true
+}
+
+// 将指定编码的文本转换为 UTF-8 字符串，并去掉开头的 BOM
+// enc 为 EncodingAuto 时自动识别编码，为 EncodingRaw 时原样返回，返回实际使用的编码
+func DecodeText(data []byte, enc Encoding) (string, Encoding, error) {
+	enc, data = stripBOM(data, enc)
+	codec, err := enc.codec()
+	if err != nil {
+		return "", enc, err
+	}
+	if codec == nil {
+		return string(data), enc, nil
+	}
+	out, _, err := transform.Bytes(codec.NewDecoder(), data)
+	if err != nil {
+		return "", enc, err
+	}
+	return string(out), enc, nil
+}
+
+// 按 enc 识别或去掉 BOM，返回实际使用的编码和去掉 BOM 后的内容
+func stripBOM(data []byte, enc Encoding) (Encoding, []byte) {
+	enc = enc.normalize()
+	if enc == EncodingAuto {
+		detected, n := detectEncoding(data[:minInt(len(data), detectEncodingSize)], len(data) > detectEncodingSize)
+		return detected, data[n:]
+	}
+	return enc, bytes.TrimPrefix(data, enc.bom())
+}
+
+// 将 UTF-8 字符串转换为指定编码，bom 为 true 时在开头写入 BOM（仅 UTF-8 和 UTF-16 有 BOM）
+// 字符串中含有目标编码无法表示的字符时返回错误
+func EncodeText(s string, enc Encoding, bom bool) ([]byte, error) {
+	enc = enc.normalize()
+	if enc == EncodingAuto || enc == EncodingRaw {
+		enc = EncodingUTF8
+	}
+	codec, err := enc.codec()
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	if bom {
+		out = append(out, enc.bom()...)
+	}
+	if codec == nil {
+		return append(out, s...), nil
+	}
+	encoded, _, err := transform.Bytes(codec.NewEncoder(), []byte(s))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, encoded...), nil
+}
+
+// 返回将 reader 中指定编码的文本转换为 UTF-8 的 reader，开头的 BOM 会被去掉
+// enc 为 EncodingAuto 时按开头 4KB 的内容识别编码，为 EncodingRaw 时直接返回 reader，返回实际使用的编码
+func NewTextReader(reader io.Reader, enc Encoding) (io.Reader, Encoding, error) {
+	enc = enc.normalize()
+	if enc == EncodingRaw {
+		return reader, enc, nil
+	}
+	buf := bufio.NewReaderSize(reader, detectEncodingSize)
+	head, err := buf.Peek(detectEncodingSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, enc, err
+	}
+	bomLen := 0
+	if enc == EncodingAuto {
+		enc, bomLen = detectEncoding(head, err == nil)
+	} else if bom := enc.bom(); bom != nil && bytes.HasPrefix(head, bom) {
+		bomLen = len(bom)
+	}
+	buf.Discard(bomLen)
+	codec, err := enc.codec()
+	if err != nil {
+		return nil, enc, err
+	}
+	if codec == nil {
+		return buf, enc, nil
+	}
+	return transform.NewReader(buf, codec.NewDecoder()), enc, nil
+}
+
+// 按指定编码读取文本文件，返回 UTF-8 字符串和实际使用的编码
+// enc 为 EncodingAuto 时自动识别编码，为 EncodingRaw 时不转换
+// content, enc, err := ReadFileWithEncoding("./conf/gbk.txt", EncodingAuto)
+func ReadFileWithEncoding(filePath string, enc Encoding) (string, Encoding, error) {
+	return defaultFS.ReadFileWithEncoding(filePath, enc)
+}
+
+// 按指定编码覆盖写入文本文件，bom 为 true 时在开头写入 BOM
+// WriteFileWithEncoding("./conf/gbk.txt", "中文", EncodingGBK, false)
+func WriteFileWithEncoding(relitivePathAndFileName string, content string, enc Encoding, bom bool) error {
+	return defaultFS.WriteFileWithEncoding(relitivePathAndFileName, content, enc, bom)
+}
+
+// 按指定编码读取文本文件，返回 UTF-8 字符串和实际使用的编码
+func (f *FS) ReadFileWithEncoding(filePath string, enc Encoding) (string, Encoding, error) {
+	file, err := f.open(filePath)
+	if err != nil {
+		return "", enc, err
+	}
+	defer file.Close()
+	reader, enc, err := NewTextReader(file, enc)
+	if err != nil {
+		return "", enc, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", enc, err
+	}
+	return string(data), enc, nil
+}
+
+// 按指定编码覆盖写入文本文件，bom 为 true 时在开头写入 BOM
+func (f *FS) WriteFileWithEncoding(relitivePathAndFileName string, content string, enc Encoding, bom bool) error {
+	data, err := EncodeText(content, enc, bom)
+	if err != nil {
+		return err
+	}
+	return f.ReWriteFile(relitivePathAndFileName, data)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
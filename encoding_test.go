@@ -0,0 +1,104 @@
+package zfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	gbk, _ := EncodeText("中文abc", EncodingGBK, false)
+	gb18030, _ := EncodeText("中文€😀", EncodingGB18030, false)
+	utf16le, _ := EncodeText("hello 中文", EncodingUTF16LE, false)
+	utf16be, _ := EncodeText("hello 中文", EncodingUTF16BE, true)
+
+	assert.Equal(t, EncodingUTF8, DetectEncoding([]byte("中文abc")))
+	assert.Equal(t, EncodingUTF8, DetectEncoding(append(bomUTF8, "abc"...)))
+	assert.Equal(t, EncodingGBK, DetectEncoding(gbk))
+	assert.Equal(t, EncodingGB18030, DetectEncoding(gb18030))
+	assert.Equal(t, EncodingUTF16LE, DetectEncoding(utf16le))
+	assert.Equal(t, EncodingUTF16BE, DetectEncoding(utf16be))
+
+	// 只检查前 4KB，截断处的不完整字符不影响识别
+	long := []byte(strings.Repeat("中", detectEncodingSize))
+	assert.Equal(t, EncodingUTF8, DetectEncoding(long))
+	long, _ = EncodeText(strings.Repeat("a中", detectEncodingSize), EncodingGBK, false)
+	assert.Equal(t, EncodingGBK, DetectEncoding(long[1:]))
+}
+
+func TestDecodeAndEncodeText(t *testing.T) {
+	for _, enc := range []Encoding{EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE, EncodingGB18030} {
+		data, err := EncodeText("中文😀\r\nabc", enc, true)
+		assert.Nil(t, err)
+		s, detected, err := DecodeText(data, EncodingAuto)
+		assert.Nil(t, err)
+		assert.Equal(t, enc, detected)
+		assert.Equal(t, "中文😀\r\nabc", s)
+	}
+
+	data, _ := EncodeText("中文", "gbk", false)
+	assert.Equal(t, []byte{0xD6, 0xD0, 0xCE, 0xC4}, data)
+	s, enc, _ := DecodeText(data, "cp936")
+	assert.Equal(t, "中文", s)
+	assert.Equal(t, EncodingGBK, enc)
+
+	_, err := EncodeText("😀", EncodingGBK, false)
+	assert.NotNil(t, err)
+	_, _, err = DecodeText(data, "big5")
+	assert.NotNil(t, err)
+}
+
+func TestReadWriteFileWithEncoding(t *testing.T) {
+	fs := NewFS(memfs.New())
+	assert.Nil(t, fs.WriteFileWithEncoding("conf/gbk.txt", "第一行\r\n第二行\r\n", EncodingGBK, false))
+	raw, _ := fs.ReadFileByte("conf/gbk.txt")
+	assert.Equal(t, 16, len(raw))
+
+	content, enc, err := fs.ReadFileWithEncoding("conf/gbk.txt", EncodingAuto)
+	assert.Nil(t, err)
+	assert.Equal(t, EncodingGBK, enc)
+	assert.Equal(t, "第一行\r\n第二行\r\n", content)
+
+	// 默认按原始字节读取，指定 EncodingAuto 时转换为 UTF-8
+	lines, err := fs.ReadFileLines("conf/gbk.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{string(raw[:6]), string(raw[8:14])}, lines)
+	content, enc, _ = fs.ReadFileWithEncoding("conf/gbk.txt", EncodingRaw)
+	assert.Equal(t, EncodingRaw, enc)
+	assert.Equal(t, string(raw), content)
+
+	r, err := fs.OpenLineReader("conf/gbk.txt", &LineOptions{Encoding: EncodingAuto})
+	assert.Nil(t, err)
+	lines = nil
+	for r.Next() {
+		lines = append(lines, r.Line())
+	}
+	r.Close()
+	assert.Equal(t, []string{"第一行", "第二行"}, lines)
+
+	assert.Nil(t, fs.WriteFileWithEncoding("conf/utf16.txt", "a\nb", EncodingUTF16LE, true))
+	r, _ = fs.OpenLineReader("conf/utf16.txt", &LineOptions{Encoding: "auto"})
+	lines = nil
+	for r.Next() {
+		lines = append(lines, r.Line())
+	}
+	r.Close()
+	assert.Equal(t, []string{"a", "b"}, lines)
+}
+
+func TestNewTextReader(t *testing.T) {
+	data, _ := EncodeText(strings.Repeat("中文", 10000), EncodingUTF16BE, true)
+	reader, enc, err := NewTextReader(bytes.NewReader(data), EncodingAuto)
+	assert.Nil(t, err)
+	assert.Equal(t, EncodingUTF16BE, enc)
+	out, _ := ioutil.ReadAll(reader)
+	assert.Equal(t, strings.Repeat("中文", 10000), string(out))
+
+	reader, _, _ = NewTextReader(bytes.NewReader(append(bomUTF8, "abc"...)), EncodingUTF8)
+	out, _ = ioutil.ReadAll(reader)
+	assert.Equal(t, "abc", string(out))
+}
@@ -0,0 +1,188 @@
+package zfile
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// 文件过滤规则，用于 CopyFolder 等遍历目录的函数
+// Exclude 使用 .gitignore 语法：后出现的规则覆盖前面的规则，"!" 开头表示重新包含，
+// "/" 结尾只匹配目录，含 "/" 的规则相对遍历的根目录匹配，"**" 匹配任意层级目录
+// Include 非空时，文件至少需匹配其中一条才会保留，目录不受 Include 影响
+// 所有规则中的路径均为相对遍历根目录、以 "/" 分隔的路径
+type Filter struct {
+	include   []ignoreRule
+	exclude   []ignoreRule
+	predicate func(relPath string, info os.FileInfo) bool
+}
+
+type ignoreRule struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// 创建空的过滤规则，保留所有文件
+// NewFilter().Exclude(".git/", "node_modules/", "*.o").Include("*.go", "*.md")
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+// 添加包含规则
+func (f *Filter) Include(patterns ...string) *Filter {
+	for _, p := range patterns {
+		if rule, ok := parseIgnoreRule(p); ok {
+			f.include = append(f.include, rule)
+		}
+	}
+	return f
+}
+
+// 添加 .gitignore 语法的排除规则
+func (f *Filter) Exclude(patterns ...string) *Filter {
+	for _, p := range patterns {
+		if rule, ok := parseIgnoreRule(p); ok {
+			f.exclude = append(f.exclude, rule)
+		}
+	}
+	return f
+}
+
+// 从 r 中按行读取 .gitignore 语法的排除规则
+func (f *Filter) ExcludeFrom(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f.Exclude(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// 设置回调，返回 false 的文件或目录被跳过，目录被跳过时不再进入
+func (f *Filter) Match(predicate func(relPath string, info os.FileInfo) bool) *Filter {
+	f.predicate = predicate
+	return f
+}
+
+// 判断相对路径 relPath 是否保留，info 为该路径的文件信息
+func (f *Filter) Allow(relPath string, info os.FileInfo) bool {
+	if f == nil {
+		return true
+	}
+	relPath = strings.Trim(relPath, "/")
+	segments := strings.Split(relPath, "/")
+	// 上级目录被排除时，其下的文件一并排除
+	for i := 1; i < len(segments); i++ {
+		if f.excluded(segments[:i], true) {
+			return false
+		}
+	}
+	if f.excluded(segments, info.IsDir()) {
+		return false
+	}
+	if !info.IsDir() && len(f.include) > 0 && !matchAnyRule(f.include, segments, false) {
+		return false
+	}
+	if f.predicate != nil && !f.predicate(relPath, info) {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) excluded(segments []string, isDir bool) bool {
+	excluded := false
+	for _, rule := range f.exclude {
+		if rule.match(segments, isDir) {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
+
+// 读取 .gitignore 语法的文件作为排除规则
+func LoadIgnoreFile(path string) (*Filter, error) {
+	return defaultFS.LoadIgnoreFile(path)
+}
+
+// 读取 .gitignore 语法的文件作为排除规则
+func (f *FS) LoadIgnoreFile(path string) (*Filter, error) {
+	file, err := f.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	filter := NewFilter()
+	if err := filter.ExcludeFrom(file); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func matchAnyRule(rules []ignoreRule, segments []string, isDir bool) bool {
+	for _, rule := range rules {
+		if rule.match(segments, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析一行 .gitignore 规则，空行与注释返回 false
+func parseIgnoreRule(line string) (rule ignoreRule, ok bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	// 不含 "/" 的规则匹配任意层级
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	rule.segments = strings.Split(line, "/")
+	if !anchored {
+		rule.segments = append([]string{"**"}, rule.segments...)
+	}
+	return rule, true
+}
+
+func (r ignoreRule) match(segments []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(r.segments, segments)
+}
+
+// 按路径分段匹配，"**" 匹配零个或多个分段
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
@@ -0,0 +1,59 @@
+package zfile
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestFilterAllow(t *testing.T) {
+	fs := NewFS(memfs.New())
+	for _, name := range []string{"main.go", "build/out.o", "web/node_modules/x.js", "web/app.js", "docs/a.md", "keep.o"} {
+		fs.ReWriteFile(name, []byte(name))
+	}
+	filter := NewFilter()
+	err := filter.ExcludeFrom(strings.NewReader("# 注释\nnode_modules/\n/build\n*.o\n!keep.o\n"))
+	assert.Nil(t, err)
+
+	allow := func(name string) bool {
+		fi, _ := fs.fs.Stat(name)
+		return filter.Allow(name, fi)
+	}
+	assert.True(t, allow("main.go"))
+	assert.False(t, allow("build"))
+	assert.False(t, allow("build/out.o"))
+	assert.False(t, allow("web/node_modules"))
+	assert.False(t, allow("web/node_modules/x.js"))
+	assert.True(t, allow("web/app.js"))
+	assert.True(t, allow("keep.o"))
+
+	filter.Include("*.go", "docs/**")
+	assert.True(t, allow("main.go"))
+	assert.True(t, allow("docs/a.md"))
+	assert.True(t, allow("docs"))
+	assert.False(t, allow("web/app.js"))
+}
+
+func TestCopyFolderWithFilter(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/.gitignore", []byte(".git/\nnode_modules/\n"))
+	fs.ReWriteFile("/src/.git/HEAD", []byte("ref"))
+	fs.ReWriteFile("/src/node_modules/x.js", []byte("x"))
+	fs.ReWriteFile("/src/main.go", []byte("package main"))
+	fs.ReWriteFile("/src/main_test.go", []byte("package main"))
+
+	filter, err := fs.LoadIgnoreFile("/src/.gitignore")
+	assert.Nil(t, err)
+	filter.Match(func(relPath string, info os.FileInfo) bool {
+		return !strings.HasSuffix(relPath, "_test.go")
+	})
+	report, err := fs.CopyFolderWithReport("/src", "/dst", &CopyOptions{Filter: filter})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Files)
+	assert.Equal(t, []string{"/src/.git", "/src/main_test.go", "/src/node_modules"}, report.Skipped)
+	assert.False(t, fs.CheckFileIsExist("/dst/.git"))
+	assert.True(t, fs.IsFile("/dst/main.go"))
+}
@@ -0,0 +1,194 @@
+package zfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Find 查找的类型
+type FindType int
+
+const (
+	FindFiles FindType = iota // 只查找文件，包括指向文件的符号链接
+	FindDirs                  // 只查找目录
+	FindAll                   // 查找文件和目录
+)
+
+// Find 结果的排序方式
+type FindSort int
+
+const (
+	SortByPath    FindSort = iota // 按路径排序，即遍历顺序：逐级按名称排序，目录中的内容紧随目录之后
+	SortByName                    // 按文件名排序，文件名相同时按路径排序
+	SortBySize                    // 按大小排序
+	SortByModTime                 // 按修改时间排序
+)
+
+// Find 的可选项，nil 表示查找 dir 下所有层级的全部文件
+type FindOptions struct {
+	// 文件名后缀，如 ".go"、"_test.go"，满足其一即可，不区分大小写，为空时不限制
+	Suffixes []string
+	// 匹配文件名的通配符，语法同 path.Match，如 "*.log.[0-9]"，为空时不限制
+	Glob string
+	// 匹配文件名的正则表达式，为 nil 时不限制
+	Regexp *regexp.Regexp
+	// 过滤规则，按相对 dir 的路径匹配，被排除的目录不再进入
+	Filter *Filter
+	// 最大查找深度，1 表示只查找 dir 下的直接子项，<=0 时不限制
+	MaxDepth int
+	// 文件大小范围（字节），MaxSize<=0 时不限制上限，只对文件有效
+	MinSize int64
+	MaxSize int64
+	// 修改时间范围 [ModifiedAfter, ModifiedBefore)，零值表示不限制
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// 查找的类型，默认只查找文件
+	Type FindType
+	// 为 true 时忽略以 "." 开头的隐藏文件，并且不进入隐藏目录
+	SkipHidden bool
+	// 结果的排序方式，Reverse 为 true 时倒序
+	Sort    FindSort
+	Reverse bool
+}
+
+// Find 查找到的文件或目录
+type FindResult struct {
+	Path    string // dir 与相对路径拼接而成的路径
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+func (r FindResult) IsDir() bool {
+	return r.Mode.IsDir()
+}
+
+// 在目录及下级目录中查找符合条件的文件或目录，结果不包含 dir 本身
+// Find("./logs", &FindOptions{Suffixes: []string{".log", ".gz"}, MaxDepth: 2, Sort: SortByModTime, Reverse: true})
+func Find(dir string, opts *FindOptions) ([]FindResult, error) {
+	return defaultFS.Find(dir, opts)
+}
+
+// 在目录及下级目录中查找符合条件的文件或目录，结果不包含 dir 本身
+func (f *FS) Find(dir string, opts *FindOptions) ([]FindResult, error) {
+	if opts == nil {
+		opts = &FindOptions{}
+	}
+	if !f.IsDir(dir) {
+		return nil, fmt.Errorf("given path does not exist: %s", dir)
+	}
+	if opts.Glob != "" {
+		if _, err := path.Match(opts.Glob, ""); err != nil {
+			return nil, err
+		}
+	}
+	suffixes := make([]string, len(opts.Suffixes))
+	for i, suffix := range opts.Suffixes {
+		suffixes[i] = strings.ToUpper(suffix) //忽略后缀匹配的大小写
+	}
+	results := make([]FindResult, 0, 30)
+	err := f.Walk(dir, func(filename string, fi os.FileInfo, err error) error {
+		if errors.Is(err, ErrSymlinkLoop) { // 忽略构成循环的链接
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if (opts.SkipHidden && strings.HasPrefix(fi.Name(), ".")) || !opts.Filter.Allow(rel, fi) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if opts.match(fi, suffixes) {
+			results = append(results, FindResult{Path: filename, Size: fi.Size(), Mode: fi.Mode(), ModTime: fi.ModTime()})
+		}
+		if fi.IsDir() && opts.MaxDepth > 0 && strings.Count(rel, "/")+1 >= opts.MaxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	opts.sort(results)
+	return results, nil
+}
+
+func (o *FindOptions) match(fi os.FileInfo, suffixes []string) bool {
+	switch {
+	case o.Type == FindFiles && fi.IsDir(), o.Type == FindDirs && !fi.IsDir():
+		return false
+	case !o.ModifiedAfter.IsZero() && fi.ModTime().Before(o.ModifiedAfter):
+		return false
+	case !o.ModifiedBefore.IsZero() && !fi.ModTime().Before(o.ModifiedBefore):
+		return false
+	}
+	if !fi.IsDir() && (fi.Size() < o.MinSize || (o.MaxSize > 0 && fi.Size() > o.MaxSize)) {
+		return false
+	}
+	name := fi.Name()
+	if o.Glob != "" {
+		if ok, _ := path.Match(o.Glob, name); !ok {
+			return false
+		}
+	}
+	if o.Regexp != nil && !o.Regexp.MatchString(name) {
+		return false
+	}
+	if len(suffixes) == 0 {
+		return true
+	}
+	upper := strings.ToUpper(name)
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(upper, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *FindOptions) sort(results []FindResult) {
+	var less func(a, b FindResult) bool
+	switch o.Sort {
+	case SortByName:
+		less = func(a, b FindResult) bool {
+			if an, bn := filepath.Base(a.Path), filepath.Base(b.Path); an != bn {
+				return an < bn
+			}
+			return a.Path < b.Path
+		}
+	case SortBySize:
+		less = func(a, b FindResult) bool { return a.Size < b.Size }
+	case SortByModTime:
+		less = func(a, b FindResult) bool { return a.ModTime.Before(b.ModTime) }
+	default: // 遍历顺序即按路径排序
+		if o.Reverse {
+			for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+				results[i], results[j] = results[j], results[i]
+			}
+		}
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if o.Reverse {
+			return less(results[j], results[i])
+		}
+		return less(results[i], results[j])
+	})
+}
@@ -0,0 +1,63 @@
+package zfile
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2020, 3, 20, 8, 0, 0, 0, time.Local)
+	for i, name := range []string{"a.go", "b.TXT", "sub/c.go", "sub/deep/d.log.1", ".hidden/e.go", ".f.go"} {
+		path := filepath.Join(dir, name)
+		ReWriteFile(path, make([]byte, (i+1)*10))
+		os.Chtimes(path, base.Add(time.Duration(i)*time.Hour), base.Add(time.Duration(i)*time.Hour))
+	}
+	paths := func(results []FindResult) []string {
+		var rels []string
+		for _, r := range results {
+			rel, _ := filepath.Rel(dir, r.Path)
+			rels = append(rels, filepath.ToSlash(rel))
+		}
+		return rels
+	}
+
+	results, err := Find(dir, &FindOptions{Suffixes: []string{".go", ".txt"}, SkipHidden: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.go", "b.TXT", "sub/c.go"}, paths(results))
+	assert.Equal(t, int64(30), results[2].Size)
+	assert.True(t, results[2].Mode.IsRegular())
+	assert.Equal(t, base.Add(2*time.Hour), results[2].ModTime)
+
+	results, _ = Find(dir, &FindOptions{Suffixes: []string{".go"}, MaxDepth: 1})
+	assert.Equal(t, []string{".f.go", "a.go"}, paths(results))
+
+	results, _ = Find(dir, &FindOptions{Type: FindDirs, SkipHidden: true})
+	assert.Equal(t, []string{"sub", "sub/deep"}, paths(results))
+	assert.True(t, results[0].IsDir())
+
+	results, _ = Find(dir, &FindOptions{Type: FindAll, MaxDepth: 2, Glob: "[a-d]*"})
+	assert.Equal(t, []string{"a.go", "b.TXT", "sub/c.go", "sub/deep"}, paths(results))
+
+	results, _ = Find(dir, &FindOptions{Regexp: regexp.MustCompile(`\.log\.\d+$`)})
+	assert.Equal(t, []string{"sub/deep/d.log.1"}, paths(results))
+
+	results, _ = Find(dir, &FindOptions{MinSize: 20, MaxSize: 40, Sort: SortBySize, Reverse: true})
+	assert.Equal(t, []string{"sub/deep/d.log.1", "sub/c.go", "b.TXT"}, paths(results))
+
+	results, _ = Find(dir, &FindOptions{ModifiedAfter: base.Add(time.Hour), ModifiedBefore: base.Add(4 * time.Hour), Sort: SortByModTime})
+	assert.Equal(t, []string{"b.TXT", "sub/c.go", "sub/deep/d.log.1"}, paths(results))
+
+	results, _ = Find(dir, &FindOptions{Filter: NewFilter().Exclude("sub/"), Sort: SortByName})
+	assert.Equal(t, []string{".f.go", "a.go", "b.TXT", ".hidden/e.go"}, paths(results))
+
+	_, err = Find(dir, &FindOptions{Glob: "["})
+	assert.NotNil(t, err)
+	_, err = Find(filepath.Join(dir, "none"), nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,390 @@
+package zfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.uber.org/zap"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/helper/polyfill"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+)
+
+// FS 将本包的文件函数绑定到一个 billy.Filesystem 上
+// 测试中可用 memfs，生产环境可用 osfs.New(root) 得到的 chroot 文件系统
+// 包级函数均委托给 Default() 返回的本地文件系统
+type FS struct {
+	fs billy.Filesystem
+	// native 为 true 时表示直接访问本地文件系统，相对路径按当前工作目录解析
+	native bool
+	// 遍历和复制目录时对符号链接的处理策略
+	symlinks SymlinkPolicy
+	// 为 true 时读写文件透明地解压和压缩，见 WithCompression
+	compression bool
+}
+
+var defaultFS = &FS{fs: polyfill.New(&osfs.OS{}), native: true}
+
+// 基于给定的 billy.Filesystem 创建 FS
+// NewFS(memfs.New()) 或 NewFS(osfs.New("/srv/app"))
+func NewFS(fs billy.Filesystem) *FS {
+	return &FS{fs: fs}
+}
+
+// 返回包级函数所使用的本地文件系统
+func Default() *FS {
+	return defaultFS
+}
+
+// 返回底层的 billy.Filesystem
+func (f *FS) Filesystem() billy.Filesystem {
+	return f.fs
+}
+
+// 返回文件在该文件系统中的绝对路径
+func (f *FS) AbsPath(name string) (string, error) {
+	if f.native {
+		return filepath.Abs(name)
+	}
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name), nil
+	}
+	return f.fs.Join(f.fs.Root(), name), nil
+}
+
+// 检察文件是否允许读
+func (f *FS) AllowRead(path string) bool {
+	file, err := f.fs.OpenFile(path, os.O_RDONLY, 0666)
+	if err != nil {
+		return false
+	}
+	file.Close()
+	return true
+}
+
+// 检察文件是否允许写
+func (f *FS) AllowWrite(path string) bool {
+	file, err := f.fs.OpenFile(path, os.O_WRONLY, 0666)
+	if err != nil {
+		return false
+	}
+	file.Close()
+	return true
+}
+
+// 打开指定文件，并从指定位置写入数据
+// 启用透明压缩时不能写入压缩文件，返回 ErrCompressionUnsupported
+func (f *FS) WriteAt(path string, b []byte, off int64) error {
+	if f.compression && compressionByName(path) != CompressionNone {
+		return fmt.Errorf("%w: cannot write %s at an offset", ErrCompressionUnsupported, path)
+	}
+	file, err := f.fs.OpenFile(path, os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Seek(off, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = file.Write(b)
+	return err
+}
+
+// 打开指定文件,并在文件末尾写入数据，文件或所在目录不存在时自动创建
+// syncWrite 为 true 时写入后 fsync
+// 启用透明压缩时，每次追加写入压缩文件的一个新的 gzip 成员或 zstd 帧，解压时按顺序拼接
+func (f *FS) WriteAppend(path string, b []byte, syncWrite ...bool) error {
+	c, err := f.writeCompression(path)
+	if err != nil {
+		return err
+	}
+	if c != CompressionNone {
+		var buf bytes.Buffer
+		err := writeCompressed(&buf, c, func(w io.Writer) error {
+			_, err := w.Write(b)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+	a, err := f.openAppender(path, &AppendOptions{Sync: len(syncWrite) > 0 && syncWrite[0]})
+	if err != nil {
+		return err
+	}
+	_, err = a.Write(b)
+	if e := a.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// 新建文件，若有重名文件则删除重建
+func (f *FS) ReCreateFile(relitivePathAndFileName string) (absPathFileName string, err error) {
+	file, err := f.create(relitivePathAndFileName)
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	absPathFileName, _ = f.AbsPath(relitivePathAndFileName)
+	return absPathFileName, nil
+}
+
+// 覆盖已有内容重新写入
+// 如果已经存在则打开文件，如果之前不存在则创建文件，然后覆盖已有内容重新写入
+func (f *FS) ReWriteFile(relitivePathAndFileName string, b []byte) error {
+	c, err := f.writeCompression(relitivePathAndFileName)
+	if err != nil {
+		return err
+	}
+	file, err := f.create(relitivePathAndFileName)
+	if err != nil {
+		return err
+	}
+	if c == CompressionNone {
+		_, err = file.Write(b)
+	} else {
+		err = writeCompressed(file, c, func(w io.Writer) error {
+			_, err := w.Write(b)
+			return err
+		})
+	}
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// 创建文件，失败时先创建所在目录再重试
+func (f *FS) create(name string) (billy.File, error) {
+	file, err := f.fs.Create(name)
+	if err == nil {
+		return file, nil
+	}
+	if err = f.fs.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return nil, err
+	}
+	return f.fs.Create(name)
+}
+
+// 复制文件，目标文件所在目录不存在，则创建目录后再复制
+func (f *FS) Copy(dstFileName, srcFileName string) (w int64, err error) {
+	srcFile, err := f.fs.Open(srcFileName)
+	if err != nil {
+		return 0, err
+	}
+	defer srcFile.Close()
+	dstFile, err := f.create(dstFileName)
+	if err != nil {
+		return 0, err
+	}
+	defer dstFile.Close()
+	return copyBuffered(dstFile, srcFile)
+}
+
+func (f *FS) CreateFolder(dir string) (err error) {
+	return f.fs.MkdirAll(dir, os.ModePerm)
+}
+
+// 读取文本文件中内容
+func (f *FS) ReadFile(file string) (context string, err error) {
+	data, err := f.ReadFileByte(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// 读取文本文件中内容为字节
+func (f *FS) ReadFileByte(filePath string) ([]byte, error) {
+	file, err := f.open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+// 读取文本文件中的行
+func (f *FS) ReadFileLines(file string) (lines []string, err error) {
+	err = f.ReadLines(file, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// 按源路径的层级不变copy，遇到错误时记录日志并继续复制其余文件
+func (f *FS) CopyFolder(srcAbsDir string, targetAbsDir string, copySubfolder ...bool) {
+	report, _ := f.CopyFolderWithReport(srcAbsDir, targetAbsDir, &CopyOptions{ContinueOnError: true})
+	for _, failure := range report.Failures {
+		zap.L().Error("复制文件夹时异常", zap.String("srcAbsDir", srcAbsDir), zap.String("path", failure.Path), zap.Error(failure.Err))
+	}
+}
+
+// 判断给定文件名是否是一个目录
+func (f *FS) IsDir(filename string) bool {
+	return f.isFileOrDir(filename, true)
+}
+
+func (f *FS) IsFile(filename string) bool {
+	return f.isFileOrDir(filename, false)
+}
+
+// 判断是文件还是目录，根据decideDir为true表示判断是否为目录；否则判断是否为文件
+func (f *FS) isFileOrDir(filename string, decideDir bool) bool {
+	fileInfo, err := f.fs.Stat(filename)
+	if err != nil {
+		return false
+	}
+	isDir := fileInfo.IsDir()
+	if decideDir {
+		return isDir
+	}
+	return !isDir
+}
+
+func (f *FS) CheckFileIsExist(filepath string) bool {
+	_, err := f.fs.Stat(filepath)
+	return !os.IsNotExist(err)
+}
+
+// 获得文件的修改时间
+func (f *FS) FileModTime(path string) (int64, error) {
+	fi, err := f.fs.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return fi.ModTime().Unix(), nil
+}
+
+// 返回文件的大小
+func (f *FS) FileSize(path string) (int64, error) {
+	fi, err := f.fs.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// 遍历目录及下级目录，查找符合后缀文件,如果suffix为空，则查找所有文件
+// 需要更多查找条件时使用 Find
+func (f *FS) GetFileListBySuffix(dirPath, suffix string) (files []string, err error) {
+	return f.findPaths(dirPath, &FindOptions{Suffixes: []string{suffix}})
+}
+
+// 遍历指定目录下的所有文件，查找符合后缀文件,不进入下一级目录搜索
+func (f *FS) GetFileListJustCurrentDirBySuffix(dirPath string, suffix string) (files []string, err error) {
+	return f.findPaths(dirPath, &FindOptions{Suffixes: []string{suffix}, MaxDepth: 1})
+}
+
+func (f *FS) findPaths(dirPath string, opts *FindOptions) ([]string, error) {
+	results, err := f.Find(dirPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, len(results))
+	for i, r := range results {
+		files[i] = r.Path
+	}
+	return files, nil
+}
+
+// 与 filepath.Walk 类似，按文件名顺序遍历 root 及其下级目录
+// 符号链接按 FS 的符号链接策略处理：SymlinkFollow 时进入指向目录的链接，
+// 构成循环的链接以 ErrSymlinkLoop 调用 walkFn 且不再进入；SymlinkError 时以 ErrSymlinkNotAllowed 调用 walkFn
+func (f *FS) Walk(root string, walkFn filepath.WalkFunc) error {
+	info, err := f.fs.Lstat(root)
+	viaLink := err == nil && isSymlink(info)
+	if viaLink && f.symlinks == SymlinkFollow {
+		info, err = f.fs.Stat(root)
+	}
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = f.walk(root, info, viaLink, walkFn, f.newLinkTracker())
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func (f *FS) walk(path string, info os.FileInfo, viaLink bool, walkFn filepath.WalkFunc, links *linkTracker) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+	_, entered, err := links.enter(path, viaLink)
+	if err != nil {
+		return walkFn(path, info, err)
+	}
+	if !entered {
+		return walkFn(path, info, &os.PathError{Op: "walk", Path: path, Err: ErrSymlinkLoop})
+	}
+	defer links.leave()
+
+	// 先回调目录本身，返回 SkipDir 时不再读取该目录；读取失败时与 filepath.WalkDir 相同，以错误再次回调
+	if err := walkFn(path, info, nil); err != nil {
+		return err
+	}
+	infos, err := f.readDir(path)
+	if err != nil {
+		return walkFn(path, info, err)
+	}
+	for _, fi := range infos {
+		filename := f.fs.Join(path, fi.Name())
+		resolved, skip, err := f.resolveEntry(filename, fi)
+		if skip {
+			continue
+		}
+		if err != nil {
+			err = walkFn(filename, fi, err)
+		} else {
+			err = f.walk(filename, resolved, isSymlink(fi), walkFn, links)
+		}
+		if err != nil {
+			if !resolved.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 读取目录并按文件名排序，memfs 等实现不保证顺序
+func (f *FS) readDir(dir string) ([]os.FileInfo, error) {
+	infos, err := f.fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+// 通过bufio实现对大文件复制的自动支持
+func copyBuffered(dst io.Writer, src io.Reader) (w int64, err error) {
+	return copyBufferedSize(dst, src, 0)
+}
+
+// 使用指定大小的缓冲区复制，size<=0 时使用 DefaultCopyBufferSize
+func copyBufferedSize(dst io.Writer, src io.Reader, size int) (w int64, err error) {
+	if size <= 0 {
+		size = DefaultCopyBufferSize
+	}
+	bw := bufio.NewWriterSize(dst, size)
+	w, err = io.Copy(bw, bufio.NewReaderSize(src, size))
+	if err != nil {
+		return 0, err
+	}
+	return w, bw.Flush()
+}
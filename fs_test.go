@@ -0,0 +1,95 @@
+package zfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestFSReWriteAndRead(t *testing.T) {
+	fs := NewFS(memfs.New())
+	err := fs.ReWriteFile("target/models/a.ctxt", []byte("hello\nchina"))
+	assert.Nil(t, err)
+
+	content, err := fs.ReadFile("target/models/a.ctxt")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello\nchina", content)
+
+	lines, err := fs.ReadFileLines("target/models/a.ctxt")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hello", "china"}, lines)
+
+	size, err := fs.FileSize("target/models/a.ctxt")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), size)
+
+	assert.True(t, fs.IsDir("target/models"))
+	assert.True(t, fs.IsFile("target/models/a.ctxt"))
+	assert.False(t, fs.CheckFileIsExist("target/models/b.ctxt"))
+
+	absPath, err := fs.ReCreateFile("target/models/x.ctxt")
+	assert.Nil(t, err)
+	assert.Equal(t, "/target/models/x.ctxt", absPath)
+}
+
+func TestFSCopyFolder(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a.go", []byte("package a"))
+	fs.ReWriteFile("/src/sub/b.GO", []byte("package b"))
+	fs.ReWriteFile("/src/sub/c.txt", []byte("c"))
+
+	fs.CopyFolder("/src", "/dst")
+	files, err := fs.GetFileListBySuffix("/dst", ".go")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/dst/a.go", "/dst/sub/b.GO"}, files)
+
+	files, err = fs.GetFileListJustCurrentDirBySuffix("/dst", ".go")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/dst/a.go"}, files)
+
+	w, err := fs.Copy("/other/c.txt", "/dst/sub/c.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), w)
+}
+
+// 记录读取过的目录
+type readDirRecorder struct {
+	billy.Filesystem
+	dirs []string
+}
+
+func (r *readDirRecorder) ReadDir(path string) ([]os.FileInfo, error) {
+	r.dirs = append(r.dirs, path)
+	return r.Filesystem.ReadDir(path)
+}
+
+func TestFSWalkSkipDir(t *testing.T) {
+	rec := &readDirRecorder{Filesystem: memfs.New()}
+	fs := NewFS(rec)
+	fs.ReWriteFile("/src/a.txt", []byte("a"))
+	fs.ReWriteFile("/src/skip/b.txt", []byte("b"))
+	fs.ReWriteFile("/src/sub/c.txt", []byte("c"))
+
+	var walked []string
+	err := fs.Walk("/src", func(path string, info os.FileInfo, err error) error {
+		walked = append(walked, path)
+		if info.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/src", "/src/a.txt", "/src/skip", "/src/sub", "/src/sub/c.txt"}, walked)
+	assert.Equal(t, []string{"/src", "/src/sub"}, rec.dirs)
+
+	// 不进入下一级目录时只读取当前目录
+	rec.dirs = nil
+	files, err := fs.GetFileListJustCurrentDirBySuffix("/src", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/src/a.txt"}, files)
+	assert.Equal(t, []string{"/src"}, rec.dirs)
+}
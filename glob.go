@@ -0,0 +1,253 @@
+package zfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 一条 Glob 规则
+type globPattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// 在 root 目录下按通配符查找文件和目录，返回相对 root 的路径，顺序与 Walk 相同
+// 规则以 "/" 分隔，相对 root 匹配："**" 匹配任意层级目录，"*"、"?"、"[a-z]"、"[!0-9]" 匹配单层中的文件名，
+// "{a,b}" 展开为多条规则，"/" 结尾只匹配目录，"!" 开头的规则排除已匹配的路径（如 "!vendor/**" 排除 vendor 目录及其下的全部内容，且不再进入该目录）
+// 只有排除规则时匹配全部路径
+// Glob("./src", "**/*.{go,mod}", "!**/*_test.go", "!vendor/**")
+func Glob(root string, patterns ...string) ([]string, error) {
+	return defaultFS.Glob(root, patterns...)
+}
+
+// 同 Glob，返回 root 的绝对路径与相对路径拼接后的路径
+func GlobAbs(root string, patterns ...string) ([]string, error) {
+	return defaultFS.GlobAbs(root, patterns...)
+}
+
+// 在 root 目录下按通配符查找文件和目录，返回相对 root 的路径
+func (f *FS) Glob(root string, patterns ...string) ([]string, error) {
+	return f.glob(root, patterns, false)
+}
+
+// 同 Glob，返回 root 的绝对路径与相对路径拼接后的路径
+func (f *FS) GlobAbs(root string, patterns ...string) ([]string, error) {
+	return f.glob(root, patterns, true)
+}
+
+func (f *FS) glob(root string, patterns []string, abs bool) ([]string, error) {
+	var include, exclude []globPattern
+	for _, p := range patterns {
+		compiled, err := compileGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range compiled {
+			if g.negate {
+				exclude = append(exclude, g)
+			} else {
+				include = append(include, g)
+			}
+		}
+	}
+	if len(include) == 0 {
+		include = []globPattern{{segments: []string{"**"}}}
+	}
+	if !f.IsDir(root) {
+		return nil, fmt.Errorf("given path does not exist: %s", root)
+	}
+	base := root
+	if abs {
+		var err error
+		if base, err = f.AbsPath(root); err != nil {
+			return nil, err
+		}
+	}
+	matches := make([]string, 0, 30)
+	err := f.Walk(root, func(filename string, fi os.FileInfo, err error) error {
+		if errors.Is(err, ErrSymlinkLoop) { // 忽略构成循环的链接
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, filename)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		segments := strings.Split(filepath.ToSlash(rel), "/")
+		isDir := fi.IsDir()
+		if matchGlob(include, segments, isDir) && !matchGlob(exclude, segments, isDir) {
+			if abs {
+				matches = append(matches, f.fs.Join(base, rel))
+			} else {
+				matches = append(matches, rel)
+			}
+		}
+		if isDir && !canDescendGlob(include, exclude, segments) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// 解析一条规则，展开其中的 "{a,b}"
+func compileGlob(pattern string) ([]globPattern, error) {
+	var negate bool
+	if strings.HasPrefix(pattern, "!") {
+		negate, pattern = true, pattern[1:]
+	}
+	expanded, err := expandBraces(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", path.ErrBadPattern, pattern)
+	}
+	compiled := make([]globPattern, 0, len(expanded))
+	for _, p := range expanded {
+		g := globPattern{negate: negate, dirOnly: strings.HasSuffix(p, "/")}
+		p = strings.Trim(strings.TrimPrefix(p, "./"), "/")
+		for _, seg := range strings.Split(p, "/") {
+			if seg == "" || seg == "." || (seg == "**" && len(g.segments) > 0 && g.segments[len(g.segments)-1] == "**") {
+				continue
+			}
+			seg = negateClass(seg)
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, pattern)
+			}
+			g.segments = append(g.segments, seg)
+		}
+		compiled = append(compiled, g)
+	}
+	return compiled, nil
+}
+
+// 展开规则中的 "{a,b}"，支持嵌套，"\" 转义的括号和 "[...]" 中的括号不展开
+func expandBraces(pattern string) ([]string, error) {
+	open, depth := -1, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			if j := strings.IndexByte(pattern[i+1:], ']'); j >= 0 {
+				i += j + 1
+			}
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			if depth--; depth > 0 {
+				continue
+			}
+			var expanded []string
+			for _, alt := range splitBraceAlternatives(pattern[open+1 : i]) {
+				more, err := expandBraces(pattern[:open] + alt + pattern[i+1:])
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, more...)
+			}
+			return expanded, nil
+		}
+	}
+	if depth > 0 {
+		return nil, path.ErrBadPattern
+	}
+	return []string{pattern}, nil
+}
+
+// 按不在嵌套括号中的 "," 分割
+func splitBraceAlternatives(s string) []string {
+	var alts []string
+	start, depth := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alts, s[start:])
+}
+
+// 将 shell 风格的 "[!...]" 转为 path.Match 支持的 "[^...]"
+func negateClass(seg string) string {
+	if !strings.Contains(seg, "[!") {
+		return seg
+	}
+	b := []byte(seg)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '\\':
+			i++
+		case b[i] == '[' && i+1 < len(b) && b[i+1] == '!':
+			b[i+1] = '^'
+		}
+	}
+	return string(b)
+}
+
+func matchGlob(patterns []globPattern, segments []string, isDir bool) bool {
+	for _, g := range patterns {
+		if (!g.dirOnly || isDir) && matchSegments(g.segments, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断目录下是否可能存在匹配的路径，用于跳过无关的目录
+func canDescendGlob(include, exclude []globPattern, segments []string) bool {
+	for _, g := range exclude {
+		// "dir/**" 排除目录下的全部内容
+		if n := len(g.segments); n > 0 && g.segments[n-1] == "**" && matchSegments(g.segments[:n-1], segments) {
+			return false
+		}
+	}
+	for _, g := range include {
+		if matchGlobPrefix(g.segments, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断 segments 之下的路径是否可能匹配 pattern
+func matchGlobPrefix(pattern, segments []string) bool {
+	for ; len(segments) > 0; pattern, segments = pattern[1:], segments[1:] {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+	}
+	return len(pattern) > 0
+}
@@ -0,0 +1,46 @@
+package zfile
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestGlob(t *testing.T) {
+	fs := NewFS(memfs.New())
+	for _, name := range []string{"go.mod", "main.go", "main_test.go", "cmd/app/app.go", "cmd/app/app.yaml", "docs/a1.md", "docs/b2.md", "vendor/x/x.go"} {
+		fs.ReWriteFile(filepath.Join("/src", name), []byte(name))
+	}
+
+	files, err := fs.Glob("/src", "**/*.go")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cmd/app/app.go", "main.go", "main_test.go", "vendor/x/x.go"}, files)
+
+	files, _ = fs.Glob("/src", "**/*.{go,mod}", "!**/*_test.go", "!vendor/**")
+	assert.Equal(t, []string{"cmd/app/app.go", "go.mod", "main.go"}, files)
+
+	files, _ = fs.Glob("/src", "docs/[!b]*.md", "cmd/*/app.{y{a,}ml,json}")
+	assert.Equal(t, []string{"cmd/app/app.yaml", "docs/a1.md"}, files)
+
+	files, _ = fs.Glob("/src", "**/", "!vendor/**")
+	assert.Equal(t, []string{"cmd", "cmd/app", "docs"}, files)
+
+	files, _ = fs.GlobAbs("/src", "./*.mod")
+	assert.Equal(t, []string{"/src/go.mod"}, files)
+
+	_, err = fs.Glob("/src", "{a,b")
+	assert.True(t, errors.Is(err, path.ErrBadPattern))
+	_, err = fs.Glob("/src", "[a-")
+	assert.True(t, errors.Is(err, path.ErrBadPattern))
+}
+
+func TestExpandBraces(t *testing.T) {
+	expanded, _ := expandBraces("a{b,c{d,e}}f{1,2}")
+	assert.Equal(t, []string{"abf1", "abf2", "acdf1", "acdf2", "acef1", "acef2"}, expanded)
+	expanded, _ = expandBraces(`a\{b,c}[{]`)
+	assert.Equal(t, []string{`a\{b,c}[{]`}, expanded)
+}
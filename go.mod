@@ -1,10 +1,15 @@
 module github.com/kuaileniu/zfile
 
-go 1.15
+go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/cespare/xxhash/v2 v2.1.2
+	github.com/klauspost/compress v1.15.0
 	github.com/kuaileniu/zstring v1.0.0
 	github.com/stretchr/testify v1.4.0
 	go.uber.org/zap v1.16.0
+	golang.org/x/text v0.3.8
 	gopkg.in/src-d/go-billy.v4 v4.3.2
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,188 @@
+package zfile
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// 文件摘要算法
+type HashAlgorithm string
+
+const (
+	HashMD5    HashAlgorithm = "md5"
+	HashSHA1   HashAlgorithm = "sha1"
+	HashSHA256 HashAlgorithm = "sha256"
+	HashXXHash HashAlgorithm = "xxhash" // 64 位 xxHash，速度快，不能用于安全校验
+)
+
+func (a HashAlgorithm) new() (hash.Hash, error) {
+	switch a {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256, "":
+		return sha256.New(), nil
+	case HashXXHash:
+		return xxhash.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm: %s", string(a))
+}
+
+// HashTree 的可选项，nil 表示全部使用默认值
+type HashTreeOptions struct {
+	// 摘要算法，为空时使用 HashSHA256
+	Algorithm HashAlgorithm
+	// 为 true 时文件和目录的权限也参与计算
+	IncludeModes bool
+	// 过滤规则，被排除的文件不参与计算
+	Filter *Filter
+}
+
+// 流式计算文件的摘要，返回十六进制字符串，algo 为空时使用 HashSHA256
+// sum, err := HashFile("./build/app.tar.gz", HashSHA256)
+func HashFile(path string, algo HashAlgorithm) (string, error) {
+	return defaultFS.HashFile(path, algo)
+}
+
+// 计算目录的 Merkle 摘要，返回十六进制字符串
+// 目录的摘要由其中各项的类型、名称、权限（可选）和摘要按名称顺序计算，与修改时间无关，
+// 内容相同的目录总是得到相同的摘要，可用于缓存构建结果或校验 CopyFolder 的复制结果
+// 符号链接按 FS 的符号链接策略处理，SymlinkKeep 时计算链接目标路径的摘要
+// sum, err := HashTree("./src", &HashTreeOptions{IncludeModes: true})
+func HashTree(dir string, opts *HashTreeOptions) (string, error) {
+	return defaultFS.HashTree(dir, opts)
+}
+
+// 流式计算文件的摘要，返回十六进制字符串
+func (f *FS) HashFile(path string, algo HashAlgorithm) (string, error) {
+	sum, err := f.hashFile(path, algo)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// 计算目录的 Merkle 摘要，返回十六进制字符串
+func (f *FS) HashTree(dir string, opts *HashTreeOptions) (string, error) {
+	if opts == nil {
+		opts = &HashTreeOptions{}
+	}
+	if _, err := opts.Algorithm.new(); err != nil {
+		return "", err
+	}
+	if !f.IsDir(dir) {
+		return "", fmt.Errorf("given path does not exist: %s", dir)
+	}
+	info, err := f.fs.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	sum, err := f.hashDir(dir, "", isSymlink(info), opts, f.newLinkTracker())
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+func (f *FS) hashFile(name string, algo HashAlgorithm) ([]byte, error) {
+	h, err := algo.new()
+	if err != nil {
+		return nil, err
+	}
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func (f *FS) hashDir(dir, rel string, viaLink bool, opts *HashTreeOptions, links *linkTracker) ([]byte, error) {
+	_, entered, err := links.enter(dir, viaLink)
+	if err != nil {
+		return nil, err
+	}
+	if !entered {
+		return nil, &os.PathError{Op: "hash", Path: dir, Err: ErrSymlinkLoop}
+	}
+	defer links.leave()
+
+	infos, err := f.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	h, _ := opts.Algorithm.new()
+	for _, fi := range infos {
+		name := f.fs.Join(dir, fi.Name())
+		childRel := path.Join(rel, fi.Name())
+		resolved, skip, err := f.resolveEntry(name, fi)
+		if err != nil {
+			return nil, err
+		}
+		if skip || !opts.Filter.Allow(childRel, resolved) {
+			continue
+		}
+		var kind byte
+		var sum []byte
+		switch {
+		case resolved.IsDir():
+			kind = 'd'
+			sum, err = f.hashDir(name, childRel, isSymlink(fi), opts, links)
+			if errors.Is(err, ErrSymlinkLoop) && isSymlink(fi) { // 构成循环的链接按链接本身计算
+				kind = 'l'
+				sum, err = f.hashLink(name, opts.Algorithm)
+			}
+		case isSymlink(resolved):
+			kind = 'l'
+			sum, err = f.hashLink(name, opts.Algorithm)
+		case resolved.Mode().IsRegular():
+			kind = 'f'
+			sum, err = f.hashFile(name, opts.Algorithm)
+		default: // 忽略设备文件、管道等
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 类型、名称、权限、摘要依次写入，名称以 0 结尾避免歧义
+		h.Write([]byte{kind})
+		io.WriteString(h, fi.Name())
+		h.Write([]byte{0})
+		if opts.IncludeModes {
+			var mode [4]byte
+			binary.BigEndian.PutUint32(mode[:], uint32(resolved.Mode().Perm()))
+			h.Write(mode[:])
+		}
+		h.Write(sum)
+	}
+	return h.Sum(nil), nil
+}
+
+// 计算链接目标路径的摘要
+func (f *FS) hashLink(name string, algo HashAlgorithm) ([]byte, error) {
+	target, err := f.fs.Readlink(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := algo.new()
+	if err != nil {
+		return nil, err
+	}
+	io.WriteString(h, target)
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,73 @@
+package zfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestHashFile(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("a.txt", []byte("hello"))
+	for algo, want := range map[HashAlgorithm]string{
+		HashMD5:    "5d41402abc4b2a76b9719d911017c592",
+		HashSHA1:   "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		HashSHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"":         "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		HashXXHash: "26c7827d889f6da3",
+	} {
+		sum, err := fs.HashFile("a.txt", algo)
+		assert.Nil(t, err)
+		assert.Equal(t, want, sum, algo)
+	}
+	_, err := fs.HashFile("a.txt", "crc32")
+	assert.NotNil(t, err)
+	_, err = fs.HashFile("none.txt", HashMD5)
+	assert.NotNil(t, err)
+}
+
+func TestHashTree(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	ReWriteFile(filepath.Join(src, "a.txt"), []byte("a"))
+	ReWriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"))
+	os.Symlink("sub", filepath.Join(src, "loop"))
+	os.Symlink("..", filepath.Join(src, "sub", "up"))
+
+	sum, err := HashTree(src, nil)
+	assert.Nil(t, err)
+	assert.Len(t, sum, 64)
+
+	_, err = CopyFolderWithReport(src, dst, nil)
+	assert.Nil(t, err)
+	// 复制时保留了链接，按默认策略计算的摘要相同
+	dstSum, err := HashTree(dst, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, sum, dstSum)
+	skip := Default().WithSymlinkPolicy(SymlinkSkip)
+	srcSum, _ := skip.HashTree(src, nil)
+	dstSum, _ = skip.HashTree(dst, nil)
+	assert.Equal(t, srcSum, dstSum)
+
+	// 与修改时间无关，与内容、名称和权限有关
+	os.Chmod(filepath.Join(src, "a.txt"), 0600)
+	same, _ := skip.HashTree(src, nil)
+	assert.Equal(t, srcSum, same)
+	withModes, _ := skip.HashTree(src, &HashTreeOptions{IncludeModes: true})
+	assert.NotEqual(t, srcSum, withModes)
+	ReWriteFile(filepath.Join(src, "sub", "b.txt"), []byte("c"))
+	changed, _ := skip.HashTree(src, nil)
+	assert.NotEqual(t, srcSum, changed)
+	os.Rename(filepath.Join(src, "sub", "b.txt"), filepath.Join(src, "sub", "c.txt"))
+	renamed, _ := skip.HashTree(src, nil)
+	assert.NotEqual(t, changed, renamed)
+
+	xx, err := HashTree(src, &HashTreeOptions{Algorithm: HashXXHash})
+	assert.Nil(t, err)
+	assert.Len(t, xx, 16)
+	_, err = HashTree(filepath.Join(dir, "none"), nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,185 @@
+package zfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// 行长度超过 LineOptions.MaxLineLength
+	ErrLineTooLong = errors.New("line too long")
+	// 由 ReadLines 的回调函数返回，用于提前结束读取，ReadLines 返回 nil
+	ErrStopLines = errors.New("stop reading lines")
+)
+
+// 按行读取的可选项，nil 表示全部使用默认值
+type LineOptions struct {
+	// 单行最大字节数（不含换行符），超过时返回 ErrLineTooLong，<=0 时不限制
+	MaxLineLength int
+	// 读缓冲区大小，<=0 时使用 DefaultCopyBufferSize
+	BufferSize int
+	// 文件的编码，读出的行转换为 UTF-8，开头的 BOM 会被去掉；默认为 EncodingRaw，不做转换，为 EncodingAuto 时自动识别
+	Encoding Encoding
+}
+
+// 逐行读取文件，用法与 bufio.Scanner 相同，但不限制行的长度
+// 返回的行不含行尾的 "\n" 或 "\r\n"，文件末尾的换行符不产生空行，指定 LineOptions.Encoding 时 GBK、UTF-16 等编码的行转换为 UTF-8
+//
+//	r, _ := OpenLineReader("app.log", nil)
+//	defer r.Close()
+//	for r.Next() {
+//		fmt.Println(r.LineNumber(), r.Line())
+//	}
+//	err := r.Err()
+type LineReader struct {
+	name   string
+	closer io.Closer
+	buf    *bufio.Reader
+	max    int
+	line   []byte
+	number int
+	err    error
+}
+
+// 打开文件用于逐行读取，读取完毕后需调用 Close
+func OpenLineReader(file string, opts *LineOptions) (*LineReader, error) {
+	return defaultFS.OpenLineReader(file, opts)
+}
+
+// 打开文件用于逐行读取，读取完毕后需调用 Close
+func (f *FS) OpenLineReader(file string, opts *LineOptions) (*LineReader, error) {
+	in, err := f.open(file)
+	if err != nil {
+		return nil, err
+	}
+	r := NewLineReader(in, opts)
+	r.name, r.closer = file, in
+	return r, nil
+}
+
+// 从 reader 中逐行读取，Close 不会关闭 reader
+func NewLineReader(reader io.Reader, opts *LineOptions) *LineReader {
+	if opts == nil {
+		opts = &LineOptions{}
+	}
+	size := opts.BufferSize
+	if size <= 0 {
+		size = DefaultCopyBufferSize
+	}
+	r := &LineReader{max: opts.MaxLineLength}
+	reader, _, r.err = NewTextReader(reader, opts.Encoding)
+	if r.err == nil {
+		r.buf = bufio.NewReaderSize(reader, size)
+	}
+	return r
+}
+
+// 读取下一行，没有更多的行或出错时返回 false
+func (r *LineReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	r.line = r.line[:0]
+	for {
+		chunk, err := r.buf.ReadSlice('\n')
+		r.line = append(r.line, chunk...)
+		// 预留 "\r\n" 的长度，避免超长的行全部读入内存
+		if r.max > 0 && len(r.line) > r.max+2 {
+			return r.fail(ErrLineTooLong)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(r.line) > 0 {
+			break
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+		break
+	}
+	r.number++
+	r.line = trimLineEnd(r.line)
+	if r.max > 0 && len(r.line) > r.max {
+		r.number--
+		return r.fail(ErrLineTooLong)
+	}
+	return true
+}
+
+func (r *LineReader) fail(err error) bool {
+	if r.name != "" {
+		r.err = fmt.Errorf("%s:%d: %w", r.name, r.number+1, err)
+	} else {
+		r.err = fmt.Errorf("line %d: %w", r.number+1, err)
+	}
+	return false
+}
+
+// 当前行，在下次调用 Next 前有效
+func (r *LineReader) Line() string {
+	return string(r.line)
+}
+
+// 当前行，返回的切片在下次调用 Next 后会被覆盖
+func (r *LineReader) Bytes() []byte {
+	return r.line
+}
+
+// 当前行的行号，从 1 开始
+func (r *LineReader) LineNumber() int {
+	return r.number
+}
+
+// 读取过程中的错误，正常读到文件末尾时返回 nil
+func (r *LineReader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+func (r *LineReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// 逐行读取文件并回调 fn，不会将整个文件读入内存
+// fn 返回 ErrStopLines 时停止读取并返回 nil，返回其他错误时停止读取并返回该错误
+// ReadLines("app.log", func(line string) error { ... }, &LineOptions{MaxLineLength: 1 << 20})
+func ReadLines(file string, fn func(line string) error, opts *LineOptions) error {
+	return defaultFS.ReadLines(file, fn, opts)
+}
+
+// 逐行读取文件并回调 fn，不会将整个文件读入内存
+func (f *FS) ReadLines(file string, fn func(line string) error, opts *LineOptions) error {
+	r, err := f.OpenLineReader(file, opts)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for r.Next() {
+		if err := fn(r.Line()); err != nil {
+			if err == ErrStopLines {
+				return nil
+			}
+			return err
+		}
+	}
+	return r.Err()
+}
+
+func trimLineEnd(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+		if n > 1 && line[n-2] == '\r' {
+			line = line[:n-2]
+		}
+	}
+	return line
+}
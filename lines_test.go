@@ -0,0 +1,56 @@
+package zfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	r := NewLineReader(strings.NewReader("a\r\nb\n\n"+long+"\nlast"), &LineOptions{BufferSize: 16})
+	var lines []string
+	for r.Next() {
+		lines = append(lines, r.Line())
+	}
+	assert.Nil(t, r.Err())
+	assert.Equal(t, []string{"a", "b", "", long, "last"}, lines)
+	assert.Equal(t, 5, r.LineNumber())
+	assert.False(t, r.Next())
+}
+
+func TestLineReaderMaxLineLength(t *testing.T) {
+	r := NewLineReader(strings.NewReader("1234\r\n12345\n"+strings.Repeat("x", 100)), &LineOptions{MaxLineLength: 5, BufferSize: 16})
+	assert.True(t, r.Next())
+	assert.True(t, r.Next())
+	assert.Equal(t, "12345", r.Line())
+	assert.False(t, r.Next())
+	assert.True(t, errors.Is(r.Err(), ErrLineTooLong))
+	assert.Equal(t, "line 3: line too long", r.Err().Error())
+}
+
+func TestReadLines(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("app.log", []byte("one\r\ntwo\r\nthree\r\n"))
+
+	lines, err := fs.ReadFileLines("app.log")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+
+	var read []string
+	err = fs.ReadLines("app.log", func(line string) error {
+		read = append(read, line)
+		if line == "two" {
+			return ErrStopLines
+		}
+		return nil
+	}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"one", "two"}, read)
+
+	_, err = fs.ReadFileLines("none.log")
+	assert.NotNil(t, err)
+}
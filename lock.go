@@ -0,0 +1,274 @@
+package zfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+var (
+	// TryLock 时锁已被其他进程或同一进程中的其他 FileLock 持有
+	ErrLocked = errors.New("file is locked")
+	// 在 LockOptions.Timeout 内未能获得锁
+	ErrLockTimeout = errors.New("timed out waiting for file lock")
+	// 当前平台不支持 flock 方式的文件锁，可改用 PID 锁文件
+	ErrLockUnsupported = errors.New("file locking unsupported on this platform")
+)
+
+// 等待锁时默认的重试间隔
+const defaultLockRetryInterval = 50 * time.Millisecond
+
+// Lock、TryLock 的可选项，nil 表示阻塞等待排他锁
+type LockOptions struct {
+	// 为 true 时获取共享锁（读锁），多个共享锁可同时持有，与排他锁互斥；PID 模式下忽略
+	Shared bool
+	// >0 时最多等待该时间，超时返回 ErrLockTimeout；<=0 时一直等待
+	Timeout time.Duration
+	// 等待 PID 锁文件或带超时等待时重试的间隔，<=0 时为 50 毫秒
+	RetryInterval time.Duration
+	// 为 true 时使用 PID 锁文件：锁文件不存在时创建并写入 "进程号@主机名"，Unlock 时删除
+	// 只有本机创建且进程已退出的锁文件视为过期并自动清除，其他主机创建的锁文件需由其自行删除
+	// 清除过期锁文件时使用同目录的 ".guard" 文件上的 flock 互斥，该文件不会被删除
+	PID bool
+}
+
+// 已获得的文件锁，使用完毕后需调用 Unlock
+// 锁是建议性的，只约束同样使用锁的进程，不会阻止其他进程直接读写文件
+type FileLock struct {
+	path    string
+	file    *os.File // flock 方式时为锁文件，PID 模式时为 nil
+	content []byte   // PID 模式时写入锁文件的内容
+	pid     bool
+	held    int32
+}
+
+// 获取 name 上的文件锁，锁文件不存在时创建，所在目录不存在时先创建目录
+// 锁文件应与被保护的文件分开，如 "app.json.lock"：WriteAtomic 以重命名的方式替换文件，直接锁定被替换的文件无效
+// l, err := Lock("./data/app.json.lock", &LockOptions{Timeout: 5 * time.Second})
+// defer l.Unlock()
+func Lock(name string, opts *LockOptions) (*FileLock, error) {
+	return defaultFS.Lock(name, opts)
+}
+
+// 尝试获取 name 上的文件锁，锁已被持有时立即返回 ErrLocked，忽略 opts.Timeout
+func TryLock(name string, opts *LockOptions) (*FileLock, error) {
+	return defaultFS.TryLock(name, opts)
+}
+
+// 在 path + ".lock" 上获取排他锁后执行 fn，fn 返回后释放锁
+// WithLock("./data/app.json", func() error { return ReWriteFile("./data/app.json", b) })
+func WithLock(path string, fn func() error) error {
+	return defaultFS.WithLock(path, fn)
+}
+
+// 获取 name 上的文件锁，只支持本地文件系统
+func (f *FS) Lock(name string, opts *LockOptions) (*FileLock, error) {
+	if opts == nil {
+		opts = &LockOptions{}
+	}
+	if !opts.PID && opts.Timeout <= 0 {
+		return f.lock(name, opts, true)
+	}
+	interval := opts.RetryInterval
+	if interval <= 0 {
+		interval = defaultLockRetryInterval
+	}
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
+	for {
+		l, err := f.lock(name, opts, false)
+		if err != ErrLocked {
+			return l, err
+		}
+		wait := interval
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return nil, fmt.Errorf("%w: %s", ErrLockTimeout, name)
+			}
+			if left < wait {
+				wait = left
+			}
+		}
+		time.Sleep(wait)
+	}
+}
+
+// 尝试获取 name 上的文件锁，锁已被持有时立即返回 ErrLocked
+func (f *FS) TryLock(name string, opts *LockOptions) (*FileLock, error) {
+	if opts == nil {
+		opts = &LockOptions{}
+	}
+	return f.lock(name, opts, false)
+}
+
+// 在 path + ".lock" 上获取排他锁后执行 fn，fn 返回后释放锁
+func (f *FS) WithLock(path string, fn func() error) error {
+	l, err := f.Lock(path+".lock", nil)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if e := l.Unlock(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (f *FS) lock(name string, opts *LockOptions, wait bool) (*FileLock, error) {
+	path, ok := f.osPath(name)
+	if !ok {
+		return nil, billy.ErrNotSupported
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if opts.PID {
+		content, err := lockPIDFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return &FileLock{path: path, content: content, pid: true, held: 1}, nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := flockFile(file, opts.Shared, wait); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &FileLock{path: path, file: file, held: 1}, nil
+}
+
+// 返回锁文件的路径
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// 释放锁，重复调用时直接返回 nil
+// flock 方式保留锁文件，删除锁文件会使其他正在等待的进程锁住已被删除的文件
+// PID 模式只在锁文件仍为本锁所写时删除锁文件，否则返回错误
+func (l *FileLock) Unlock() error {
+	if !atomic.CompareAndSwapInt32(&l.held, 1, 0) {
+		return nil
+	}
+	if l.pid {
+		return unlockPIDFile(l.path, l.content)
+	}
+	err := funlockFile(l.file)
+	if e := l.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// 创建 PID 锁文件并返回写入的内容，锁文件已存在且未过期时返回 ErrLocked
+// 先写入临时文件再硬链接为锁文件，其他进程不会读到未写完的内容
+func lockPIDFile(path string) ([]byte, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	content := []byte(fmt.Sprintf("%d@%s\n", os.Getpid(), host))
+	tmp, err := createSiblingTemp(path)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err := os.Link(tmp.Name(), path)
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		var stale bool
+		err = withPIDGuard(path, func() (err error) {
+			stale, err = removeStalePIDFile(path, host)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !stale {
+			return nil, ErrLocked
+		}
+	}
+}
+
+// 锁文件由本机 host 创建且其中的进程已退出时删除锁文件并返回 true，锁文件已不存在时同样返回 true 以便重试
+// 其他主机的进程号无法在本机判断，不视为过期；需在 withPIDGuard 中调用
+func removeStalePIDFile(path, host string) (bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	owner := string(bytes.TrimSpace(data))
+	i := strings.LastIndex(owner, "@")
+	if i < 0 || owner[i+1:] != host {
+		return false, nil
+	}
+	pid, err := strconv.Atoi(owner[:i])
+	if err == nil && processAlive(pid) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// 删除内容为 content 的 PID 锁文件，锁文件已被其他进程清除或重新创建时不删除并返回错误
+func unlockPIDFile(path string, content []byte) error {
+	return withPIDGuard(path, func() error {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, content) {
+			return fmt.Errorf("%s: lock file is now held by %s", path, bytes.TrimSpace(data))
+		}
+		return os.Remove(path)
+	})
+}
+
+// 在 path + ".guard" 的排他 flock 中执行 fn，使清除过期锁文件和 Unlock 互斥
+// 检查锁文件内容与删除之间不会被其他进程插入；guard 文件保留不删除，不支持 flock 的平台上直接执行 fn
+func withPIDGuard(path string, fn func() error) error {
+	guard, err := os.OpenFile(path+".guard", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer guard.Close()
+	if err := flockFile(guard, false, true); err != nil {
+		if err != ErrLockUnsupported {
+			return err
+		}
+		return fn()
+	}
+	defer funlockFile(guard)
+	return fn()
+}
@@ -0,0 +1,21 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package zfile
+
+import (
+	"os"
+)
+
+func flockFile(file *os.File, shared, wait bool) error {
+	return ErrLockUnsupported
+}
+
+func funlockFile(file *os.File) error {
+	return ErrLockUnsupported
+}
+
+// 无法判断进程是否在运行，PID 锁文件不会被视为过期
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,154 @@
+package zfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "locks", "app.lock")
+	l, err := Lock(name, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, name, l.Path())
+
+	_, err = TryLock(name, nil)
+	assert.Equal(t, ErrLocked, err)
+	_, err = TryLock(name, &LockOptions{Shared: true})
+	assert.Equal(t, ErrLocked, err)
+	start := time.Now()
+	_, err = Lock(name, &LockOptions{Timeout: 100 * time.Millisecond, RetryInterval: 10 * time.Millisecond})
+	assert.True(t, errors.Is(err, ErrLockTimeout))
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	// 阻塞等待的 Lock 在锁释放后获得锁
+	acquired := make(chan *FileLock)
+	go func() {
+		l, _ := Lock(name, nil)
+		acquired <- l
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, l.Unlock())
+	assert.Nil(t, l.Unlock())
+	select {
+	case l = <-acquired:
+		assert.NotNil(t, l)
+		l.Unlock()
+	case <-time.After(3 * time.Second):
+		t.Fatal("lock not acquired after unlock")
+	}
+
+	// 共享锁可同时持有，与排他锁互斥
+	r1, err := TryLock(name, &LockOptions{Shared: true})
+	assert.Nil(t, err)
+	r2, err := TryLock(name, &LockOptions{Shared: true})
+	assert.Nil(t, err)
+	_, err = TryLock(name, nil)
+	assert.Equal(t, ErrLocked, err)
+	r1.Unlock()
+	r2.Unlock()
+
+	_, err = NewFS(memfs.New()).Lock("/app.lock", nil)
+	assert.Equal(t, billy.ErrNotSupported, err)
+}
+
+func TestLockPID(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.pid")
+	opts := &LockOptions{PID: true, Timeout: 100 * time.Millisecond, RetryInterval: 10 * time.Millisecond}
+	host, _ := os.Hostname()
+	owner := strconv.Itoa(os.Getpid()) + "@" + host + "\n"
+	l, err := Lock(name, opts)
+	assert.Nil(t, err)
+	pid, _ := ReadFile(name)
+	assert.Equal(t, owner, pid)
+
+	_, err = TryLock(name, opts)
+	assert.Equal(t, ErrLocked, err)
+	_, err = Lock(name, opts)
+	assert.True(t, errors.Is(err, ErrLockTimeout))
+	assert.Nil(t, l.Unlock())
+	assert.False(t, IsFile(name))
+
+	// 其他主机创建的锁文件无法判断进程是否在运行，不视为过期
+	ReWriteFile(name, []byte("999999999@other-host\n"))
+	_, err = TryLock(name, opts)
+	assert.Equal(t, ErrLocked, err)
+
+	// 本机持有者进程已退出的锁文件视为过期
+	ReWriteFile(name, []byte("999999999@"+host+"\n"))
+	l, err = TryLock(name, opts)
+	assert.Nil(t, err)
+	pid, _ = ReadFile(name)
+	assert.Equal(t, owner, pid)
+	l.Unlock()
+}
+
+func TestLockPIDReclaim(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.pid")
+	host, _ := os.Hostname()
+	opts := &LockOptions{PID: true}
+	for round := 0; round < 20; round++ {
+		// 多个协程同时清除同一个过期的锁文件，只有一个能获得锁
+		ReWriteFile(name, []byte("999999999@"+host+"\n"))
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var held []*FileLock
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if l, err := TryLock(name, opts); err == nil {
+					mu.Lock()
+					held = append(held, l)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Len(t, held, 1)
+		for _, l := range held {
+			assert.Nil(t, l.Unlock())
+		}
+	}
+
+	// 锁文件已被其他持有者替换时 Unlock 不删除
+	l, err := TryLock(name, opts)
+	assert.Nil(t, err)
+	ReWriteFile(name, []byte("1@other-host\n"))
+	assert.NotNil(t, l.Unlock())
+	content, _ := ReadFile(name)
+	assert.Equal(t, "1@other-host\n", content)
+}
+
+func TestWithLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "counter.txt")
+	ReWriteFile(name, []byte("0"))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			WithLock(name, func() error {
+				s, _ := ReadFile(name)
+				n, _ := strconv.Atoi(s)
+				time.Sleep(time.Millisecond)
+				return ReWriteFileAtomic(name, []byte(strconv.Itoa(n+1)))
+			})
+		}()
+	}
+	wg.Wait()
+	s, _ := ReadFile(name)
+	assert.Equal(t, "10", s)
+	assert.True(t, IsFile(name+".lock"))
+
+	err := WithLock(name, func() error { return ErrStopWalk })
+	assert.Equal(t, ErrStopWalk, err)
+}
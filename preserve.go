@@ -0,0 +1,149 @@
+package zfile
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+)
+
+// 复制文件时需要保留的元数据
+type Preserve uint
+
+const (
+	// 保留权限位，包括 setuid、setgid、sticky
+	PreserveMode Preserve = 1 << iota
+	// 保留修改时间和访问时间
+	PreserveTimes
+	// 保留属主和属组，无权限时忽略
+	PreserveOwner
+	// 保留扩展属性，仅 linux 支持，无权限的属性忽略
+	PreserveXattrs
+
+	PreserveAll = PreserveMode | PreserveTimes | PreserveOwner | PreserveXattrs
+)
+
+// 复制文件并保留 preserve 指定的元数据
+// CopyPreserve(`/data/b.txt`, `/data/a.txt`, PreserveMode|PreserveTimes)
+func CopyPreserve(dstFileName, srcFileName string, preserve Preserve) (w int64, err error) {
+	return defaultFS.CopyPreserve(dstFileName, srcFileName, preserve)
+}
+
+// 复制文件并保留 preserve 指定的元数据
+// 不支持保留时在复制前返回 billy.ErrNotSupported；目标文件以源文件的权限位创建，复制过程中不会比源文件更宽松
+func (f *FS) CopyPreserve(dstFileName, srcFileName string, preserve Preserve) (w int64, err error) {
+	if !f.canPreserve(preserve) {
+		return 0, billy.ErrNotSupported
+	}
+	fi, err := f.fs.Stat(srcFileName)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.fs.MkdirAll(filepath.Dir(dstFileName), os.ModePerm); err != nil {
+		return 0, err
+	}
+	w, err = f.copyFile(srcFileName, dstFileName, fi.Mode(), 0)
+	if err != nil {
+		return 0, err
+	}
+	return w, f.preserveMetadata(srcFileName, dstFileName, fi, preserve)
+}
+
+// 判断能否保留 preserve 指定的元数据：本地文件系统全部支持，其他文件系统需实现 billy.Change 且不支持扩展属性
+func (f *FS) canPreserve(preserve Preserve) bool {
+	if preserve == 0 {
+		return true
+	}
+	if _, ok := f.osPath("."); ok {
+		return true
+	}
+	_, ok := f.fs.(billy.Change)
+	return ok && preserve&PreserveXattrs == 0
+}
+
+// 将 src 的元数据应用到 target 上，fi 为 src 的文件信息
+// 本地文件系统直接调用 os 包，其他文件系统需实现 billy.Change，否则返回 billy.ErrNotSupported
+func (f *FS) preserveMetadata(src, target string, fi os.FileInfo, preserve Preserve) error {
+	if preserve == 0 {
+		return nil
+	}
+	if isSymlink(fi) {
+		var err error
+		if fi, err = f.fs.Stat(src); err != nil {
+			return err
+		}
+	}
+	srcPath, srcOK := f.osPath(src)
+	targetPath, targetOK := f.osPath(target)
+	if srcOK && targetOK {
+		return preserveOSMetadata(srcPath, targetPath, fi, preserve)
+	}
+	change, ok := f.fs.(billy.Change)
+	if !ok || preserve&PreserveXattrs != 0 {
+		return billy.ErrNotSupported
+	}
+	if preserve&PreserveOwner != 0 {
+		if uid, gid, ok := fileOwner(fi); ok {
+			if err := change.Chown(target, uid, gid); err != nil && !os.IsPermission(err) {
+				return err
+			}
+		}
+	}
+	if preserve&PreserveMode != 0 {
+		if err := change.Chmod(target, preservedMode(fi)); err != nil {
+			return err
+		}
+	}
+	if preserve&PreserveTimes != 0 {
+		return change.Chtimes(target, fileAtime(fi), fi.ModTime())
+	}
+	return nil
+}
+
+func preserveOSMetadata(src, target string, fi os.FileInfo, preserve Preserve) error {
+	// chown 会清除 setuid 位，需先于 chmod 执行
+	if preserve&PreserveOwner != 0 {
+		if uid, gid, ok := fileOwner(fi); ok {
+			if err := os.Chown(target, uid, gid); err != nil && !os.IsPermission(err) {
+				return err
+			}
+		}
+	}
+	if preserve&PreserveMode != 0 {
+		if err := os.Chmod(target, preservedMode(fi)); err != nil {
+			return err
+		}
+	}
+	if preserve&PreserveXattrs != 0 {
+		if err := copyXattrs(src, target); err != nil {
+			return err
+		}
+	}
+	if preserve&PreserveTimes != 0 {
+		return os.Chtimes(target, fileAtime(fi), fi.ModTime())
+	}
+	return nil
+}
+
+func preservedMode(fi os.FileInfo) os.FileMode {
+	return fi.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// 返回 name 在本地文件系统中的路径，底层不是本地文件系统时返回 false
+func (f *FS) osPath(name string) (string, bool) {
+	if f.native {
+		return name, true
+	}
+	var b billy.Basic = f.fs
+	for {
+		switch u := b.(type) {
+		case *osfs.OS:
+			return f.fs.Join(f.fs.Root(), name), true
+		case interface{ Underlying() billy.Basic }:
+			b = u.Underlying()
+		default:
+			return "", false
+		}
+	}
+}
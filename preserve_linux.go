@@ -0,0 +1,63 @@
+package zfile
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"time"
+)
+
+// 返回文件的访问时间
+func fileAtime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	}
+	return fi.ModTime()
+}
+
+// 复制全部扩展属性，文件系统不支持或无权限设置的属性忽略
+func copyXattrs(src, target string) error {
+	size, err := syscall.Listxattr(src, nil)
+	if err != nil || size == 0 {
+		return ignoreXattrErr(err)
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(src, buf)
+	if err != nil {
+		return ignoreXattrErr(err)
+	}
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+		n, err := syscall.Getxattr(src, attr, nil)
+		if err != nil {
+			if err = ignoreXattrErr(err); err != nil {
+				return &os.PathError{Op: "getxattr", Path: src, Err: err}
+			}
+			continue
+		}
+		value := make([]byte, n)
+		if n, err = syscall.Getxattr(src, attr, value); err != nil {
+			if err = ignoreXattrErr(err); err != nil {
+				return &os.PathError{Op: "getxattr", Path: src, Err: err}
+			}
+			continue
+		}
+		if err := syscall.Setxattr(target, attr, value[:n], 0); err != nil {
+			if err = ignoreXattrErr(err); err != nil {
+				return &os.PathError{Op: "setxattr", Path: target, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+func ignoreXattrErr(err error) error {
+	switch err {
+	case nil, syscall.ENOTSUP, syscall.EPERM, syscall.EACCES, syscall.ENODATA:
+		return nil
+	}
+	return err
+}
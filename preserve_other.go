@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package zfile
+
+import (
+	"os"
+	"time"
+)
+
+// 非 linux 平台无法可靠取得访问时间，使用修改时间代替
+func fileAtime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
+
+func copyXattrs(src, target string) error {
+	return nil
+}
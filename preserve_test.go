@@ -0,0 +1,65 @@
+package zfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestCopyPreserve(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	ReWriteFile(src, []byte("hello"))
+	mtime := time.Date(2020, 3, 20, 8, 0, 0, 0, time.Local)
+	os.Chmod(src, 0751)
+	os.Chtimes(src, mtime, mtime)
+
+	_, err := CopyPreserve(filepath.Join(dir, "b.txt"), src, PreserveAll)
+	assert.Nil(t, err)
+
+	// 不保留元数据时目标文件同样以源文件的权限位创建
+	os.Chmod(src, 0600)
+	_, err = CopyPreserve(filepath.Join(dir, "sub", "c.txt"), src, 0)
+	assert.Nil(t, err)
+	fi, _ := os.Stat(filepath.Join(dir, "sub", "c.txt"))
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	os.Chmod(src, 0751)
+	fi, _ = os.Stat(filepath.Join(dir, "b.txt"))
+	assert.Equal(t, os.FileMode(0751), fi.Mode().Perm())
+	modTime, _ := FileModTime(filepath.Join(dir, "b.txt"))
+	assert.Equal(t, mtime.Unix(), modTime)
+
+	// memfs 不支持修改时间，不保留元数据时可以正常复制
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/a.txt", []byte("hello"))
+	_, err = fs.CopyPreserve("/b.txt", "/a.txt", PreserveTimes)
+	assert.True(t, errors.Is(err, billy.ErrNotSupported))
+	assert.False(t, fs.CheckFileIsExist("/b.txt"))
+	_, err = fs.CopyPreserve("/c.txt", "/a.txt", 0)
+	assert.Nil(t, err)
+	content, _ := fs.ReadFile("/c.txt")
+	assert.Equal(t, "hello", content)
+}
+
+func TestCopyFolderPreserve(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	target := filepath.Join(t.TempDir(), "target")
+	ReWriteFile(filepath.Join(src, "sub", "a.txt"), []byte("hello"))
+	mtime := time.Date(2020, 3, 20, 8, 0, 0, 0, time.Local)
+	for _, name := range []string{"sub/a.txt", "sub", "."} {
+		os.Chtimes(filepath.Join(src, name), mtime, mtime)
+	}
+
+	_, err := CopyFolderWithReport(src, target, &CopyOptions{Preserve: PreserveMode | PreserveTimes, Workers: 2})
+	assert.Nil(t, err)
+	for _, name := range []string{"sub/a.txt", "sub", "."} {
+		modTime, _ := FileModTime(filepath.Join(target, name))
+		assert.Equal(t, mtime.Unix(), modTime, name)
+	}
+}
@@ -0,0 +1,268 @@
+package zfile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 备份文件名中时间戳的格式，按字符串排序即按时间排序
+const rotateTimeFormat = "20060102-150405.000000"
+
+// OpenRotateWriter 的可选项
+type RotateOptions struct {
+	// 文件超过该字节数时轮转，<=0 时不按大小轮转
+	MaxSize int64
+	// 每隔该时间轮转一次，<=0 时不按时间轮转
+	Interval time.Duration
+	// 保留的备份文件数，<=0 时保留全部
+	MaxBackups int
+	// 为 true 时用 gzip 压缩备份文件，备份文件名以 .gz 结尾
+	Compress bool
+	// 为 true 时每次写入后 fsync
+	Sync bool
+}
+
+// 按大小或时间自动轮转的日志文件，可并发使用
+// 备份文件名为 "原文件名.时间戳"，如 app.log.20201017-150405.000000
+// 实现 zapcore.WriteSyncer，可直接用于 zapcore.NewCore(encoder, w, level)
+type RotateWriter struct {
+	mu       sync.Mutex
+	cleanMu  sync.Mutex // 压缩和清理备份在 mu 之外进行，由 cleanMu 保证同一时刻只有一个
+	fs       *FS
+	path     string
+	opts     RotateOptions
+	file     *Appender // 轮转失败且无法重新打开时为 nil，下次写入时重新打开
+	size     int64
+	limit    int64            // 按大小轮转的上限，轮转失败后推迟到再写入 MaxSize 字节时重试
+	rotateAt time.Time        // 下次按时间轮转的时刻，轮转失败后推迟一个 Interval 重试
+	now      func() time.Time // 便于测试替换
+}
+
+// 打开轮转写入的日志文件，已存在的文件继续追加
+// w, _ := OpenRotateWriter("logs/app.log", &RotateOptions{MaxSize: 100 << 20, MaxBackups: 7, Compress: true})
+func OpenRotateWriter(path string, opts *RotateOptions) (*RotateWriter, error) {
+	return defaultFS.OpenRotateWriter(path, opts)
+}
+
+// 打开轮转写入的日志文件，已存在的文件继续追加
+func (f *FS) OpenRotateWriter(path string, opts *RotateOptions) (*RotateWriter, error) {
+	w := &RotateWriter{fs: f, path: path, now: time.Now}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotateWriter) open() error {
+	file, err := w.fs.OpenAppender(w.path, &AppendOptions{Sync: w.opts.Sync})
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.size, _ = w.fs.FileSize(w.path)
+	w.limit = w.opts.MaxSize
+	if w.opts.Interval > 0 {
+		w.rotateAt = w.now().Add(w.opts.Interval)
+	}
+	return nil
+}
+
+// 写入日志，写入前按需轮转，轮转后的压缩和清理不阻塞其他写入
+// 轮转失败时仍写入原文件，返回写入的字节数和轮转的错误，不会丢失日志
+func (w *RotateWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	var backup string
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			w.mu.Unlock()
+			return 0, err
+		}
+	}
+	if w.shouldRotate(len(p)) {
+		backup, err = w.rotate()
+		if w.file == nil {
+			w.mu.Unlock()
+			return 0, err
+		}
+	}
+	n, e := w.file.Write(p)
+	w.size += int64(n)
+	w.mu.Unlock()
+	if err == nil {
+		err = e
+	}
+	if e := w.cleanup(backup); err == nil {
+		err = e
+	}
+	return n, err
+}
+
+func (w *RotateWriter) shouldRotate(n int) bool {
+	if w.limit > 0 && w.size > 0 && w.size+int64(n) > w.limit {
+		return true
+	}
+	return w.opts.Interval > 0 && !w.now().Before(w.rotateAt)
+}
+
+// 立即轮转
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	backup, err := w.rotate()
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return w.cleanup(backup)
+}
+
+// fsync 当前日志文件
+func (w *RotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.file.Sync()
+}
+
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
+
+// 将当前文件重命名为备份文件并重新打开，返回备份文件名，原文件不存在时返回空字符串
+// 任何一步失败时重新以追加方式打开原文件，之后的写入仍能继续；重新打开也失败时 w.file 为 nil
+func (w *RotateWriter) rotate() (string, error) {
+	if w.file == nil {
+		return "", os.ErrClosed
+	}
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return "", w.reopen(err)
+	}
+	stamp := w.path + "." + w.now().Format(rotateTimeFormat)
+	backup := stamp
+	for i := 1; w.fs.CheckFileIsExist(backup) || w.fs.CheckFileIsExist(backup+".gz"); i++ {
+		backup = stamp + "-" + strconv.Itoa(i)
+	}
+	if err := w.fs.fs.Rename(w.path, backup); err != nil {
+		if !os.IsNotExist(err) {
+			return "", w.reopen(err)
+		}
+		backup = ""
+	}
+	if err := w.open(); err != nil {
+		return "", w.reopen(err)
+	}
+	return backup, nil
+}
+
+// 轮转失败后重新打开原文件，并推迟下次按大小轮转的时机，避免每次写入都重试，返回轮转的错误
+func (w *RotateWriter) reopen(err error) error {
+	if e := w.open(); e != nil {
+		return fmt.Errorf("%w (reopen %s: %v)", err, w.path, e)
+	}
+	if w.opts.MaxSize > 0 {
+		w.limit = w.size + w.opts.MaxSize
+	}
+	return err
+}
+
+// 按需压缩刚轮转出的备份文件，并删除多余的备份，backup 为空表示没有新的备份
+func (w *RotateWriter) cleanup(backup string) error {
+	if backup == "" {
+		return nil
+	}
+	w.cleanMu.Lock()
+	defer w.cleanMu.Unlock()
+	if w.opts.Compress {
+		if err := w.compress(backup); err != nil {
+			return err
+		}
+	}
+	return w.removeOldBackups()
+}
+
+// 压缩备份文件后删除原备份
+func (w *RotateWriter) compress(backup string) (err error) {
+	src, err := w.fs.fs.Open(backup)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := w.fs.fs.Create(backup + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = copyBuffered(zw, src)
+	if e := zw.Close(); err == nil {
+		err = e
+	}
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		w.fs.fs.Remove(backup + ".gz")
+		return err
+	}
+	return w.fs.fs.Remove(backup)
+}
+
+// 返回现有的备份文件，按时间从新到旧排列
+func (w *RotateWriter) backups() ([]string, error) {
+	dir, base := filepath.Split(w.path)
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := w.fs.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := base + "."
+	var backups []string
+	for _, fi := range infos {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// 时间戳以数字开头，避免误删 app.log.lock 等文件
+		if rest := name[len(prefix):]; rest == "" || rest[0] < '0' || rest[0] > '9' {
+			continue
+		}
+		backups = append(backups, w.fs.fs.Join(dir, name))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+func (w *RotateWriter) removeOldBackups() error {
+	if w.opts.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	for i := w.opts.MaxBackups; i < len(backups); i++ {
+		if err := w.fs.fs.Remove(backups[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
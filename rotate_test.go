@@ -0,0 +1,114 @@
+package zfile
+
+import (
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestRotateWriterBySize(t *testing.T) {
+	// memfs 的 Rename 按字符串前缀移动文件，会连带移动备份文件，这里使用本地文件系统
+	name := filepath.Join(t.TempDir(), "logs", "app.log")
+	w, err := OpenRotateWriter(name, &RotateOptions{MaxSize: 10, MaxBackups: 2, Compress: true})
+	assert.Nil(t, err)
+	now := time.Date(2020, 3, 20, 8, 0, 0, 0, time.UTC)
+	w.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("12345678\n"))
+	}
+	assert.Nil(t, w.Close())
+
+	backups, _ := w.backups()
+	assert.Equal(t, []string{name + ".20200320-080003.000000.gz", name + ".20200320-080002.000000.gz"}, backups)
+	file, _ := os.Open(backups[0])
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	assert.Nil(t, err)
+	data, _ := ioutil.ReadAll(zr)
+	assert.Equal(t, "12345678\n", string(data))
+	content, _ := ReadFile(name)
+	assert.Equal(t, "12345678\n", content)
+}
+
+func TestRotateWriterByInterval(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2020, 3, 20, 8, 0, 0, 0, time.UTC)
+	w, _ := OpenRotateWriter(filepath.Join(dir, "app.log"), &RotateOptions{Interval: time.Hour})
+	defer w.Close()
+	w.now = func() time.Time { return now }
+	w.rotateAt = now.Add(time.Hour)
+
+	w.Write([]byte("a"))
+	now = now.Add(time.Hour)
+	w.Write([]byte("b"))
+	backups, _ := w.backups()
+	assert.Equal(t, []string{filepath.Join(dir, "app.log.20200320-090000.000000")}, backups)
+
+	var ws zapcore.WriteSyncer = w
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), ws, zap.InfoLevel))
+	logger.Info("hello")
+	assert.Nil(t, logger.Sync())
+	content, _ := ReadFile(filepath.Join(dir, "app.log"))
+	assert.Contains(t, content, `"msg":"hello"`)
+}
+
+// Rename 总是失败的文件系统
+type renameFailFS struct {
+	billy.Filesystem
+}
+
+func (renameFailFS) Rename(from, to string) error {
+	return errors.New("rename failed")
+}
+
+func TestRotateWriterRenameFail(t *testing.T) {
+	fs := NewFS(renameFailFS{memfs.New()})
+	w, err := fs.OpenRotateWriter("/logs/app.log", nil)
+	assert.Nil(t, err)
+	w.Write([]byte("a"))
+	assert.NotNil(t, w.Rotate())
+
+	// 轮转失败后继续追加写入原文件
+	_, err = w.Write([]byte("b"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	content, _ := fs.ReadFile("/logs/app.log")
+	assert.Equal(t, "ab", content)
+}
+
+func TestRotateWriterRenameFailBySize(t *testing.T) {
+	fs := NewFS(renameFailFS{memfs.New()})
+	w, err := fs.OpenRotateWriter("/logs/app.log", &RotateOptions{MaxSize: 4})
+	assert.Nil(t, err)
+	defer w.Close()
+	n, err := w.Write([]byte("123"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	// 轮转失败时仍然写入，之后写满 MaxSize 字节前不再重试
+	n, err = w.Write([]byte("45"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, n)
+	for _, s := range []string{"6", "7"} {
+		n, err = w.Write([]byte(s))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+	}
+	_, err = w.Write([]byte("8901"))
+	assert.NotNil(t, err)
+	content, _ := fs.ReadFile("/logs/app.log")
+	assert.Equal(t, "12345678901", content)
+}
@@ -0,0 +1,167 @@
+package zfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 读取 JSON 文件并解析到 v 中
+// var conf Config; err := ReadJSON("./conf/app.json", &conf)
+func ReadJSON(file string, v interface{}) error {
+	return defaultFS.ReadJSON(file, v)
+}
+
+// 将 v 以缩进格式写入 JSON 文件，原子地覆盖写入，目录不存在时先创建目录
+// WriteJSON("./conf/app.json", conf)
+func WriteJSON(file string, v interface{}) error {
+	return defaultFS.WriteJSON(file, v)
+}
+
+// 读取 YAML 文件并解析到 v 中
+func ReadYAML(file string, v interface{}) error {
+	return defaultFS.ReadYAML(file, v)
+}
+
+// 将 v 写入 YAML 文件，原子地覆盖写入，目录不存在时先创建目录
+func WriteYAML(file string, v interface{}) error {
+	return defaultFS.WriteYAML(file, v)
+}
+
+// 读取 TOML 文件并解析到 v 中
+func ReadTOML(file string, v interface{}) error {
+	return defaultFS.ReadTOML(file, v)
+}
+
+// 将 v 写入 TOML 文件，原子地覆盖写入，目录不存在时先创建目录
+func WriteTOML(file string, v interface{}) error {
+	return defaultFS.WriteTOML(file, v)
+}
+
+// 逐行读取 JSON Lines 文件，每个非空行回调一次 fn，由 fn 调用 decode 将该行解析到自己的变量中
+// 不会将整个文件读入内存，fn 返回 ErrStopLines 时停止读取并返回 nil
+// ReadJSONLines("./data/events.jsonl", func(decode func(v interface{}) error) error { var ev Event; return decode(&ev) })
+func ReadJSONLines(file string, fn func(decode func(v interface{}) error) error) error {
+	return defaultFS.ReadJSONLines(file, fn)
+}
+
+// 以 JSON Lines 格式写入文件，由 write 多次调用 encode，每次写入一行，原子地覆盖写入
+// WriteJSONLines("./data/events.jsonl", func(encode func(v interface{}) error) error { return encode(ev) })
+func WriteJSONLines(file string, write func(encode func(v interface{}) error) error) error {
+	return defaultFS.WriteJSONLines(file, write)
+}
+
+// 读取 JSON 文件并解析到 v 中
+func (f *FS) ReadJSON(file string, v interface{}) error {
+	return f.decodeFile(file, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(v)
+	})
+}
+
+// 将 v 以缩进格式写入 JSON 文件，不转义 HTML 字符
+func (f *FS) WriteJSON(file string, v interface{}) error {
+	return f.WriteAtomic(file, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
+
+// 读取 YAML 文件并解析到 v 中
+func (f *FS) ReadYAML(file string, v interface{}) error {
+	return f.decodeFile(file, func(r io.Reader) error {
+		err := yaml.NewDecoder(r).Decode(v)
+		if err == io.EOF { // 空文件
+			return nil
+		}
+		return err
+	})
+}
+
+// 将 v 写入 YAML 文件
+func (f *FS) WriteYAML(file string, v interface{}) error {
+	return f.WriteAtomic(file, func(w io.Writer) error {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// 读取 TOML 文件并解析到 v 中
+func (f *FS) ReadTOML(file string, v interface{}) error {
+	return f.decodeFile(file, func(r io.Reader) error {
+		_, err := toml.NewDecoder(r).Decode(v)
+		return err
+	})
+}
+
+// 将 v 写入 TOML 文件
+func (f *FS) WriteTOML(file string, v interface{}) error {
+	return f.WriteAtomic(file, func(w io.Writer) error {
+		return toml.NewEncoder(w).Encode(v)
+	})
+}
+
+// 逐行读取 JSON Lines 文件，每个非空行回调一次 fn
+func (f *FS) ReadJSONLines(file string, fn func(decode func(v interface{}) error) error) error {
+	r, err := f.OpenLineReader(file, nil)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for r.Next() {
+		line := r.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		n := r.LineNumber()
+		err := fn(func(v interface{}) error {
+			if err := json.Unmarshal(line, v); err != nil {
+				return fmt.Errorf("%s:%d: %w", file, n, err)
+			}
+			return nil
+		})
+		if err != nil {
+			if err == ErrStopLines {
+				return nil
+			}
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// 以 JSON Lines 格式写入文件，由 write 多次调用 encode，每次写入一行
+func (f *FS) WriteJSONLines(file string, write func(encode func(v interface{}) error) error) error {
+	return f.WriteAtomic(file, func(w io.Writer) error {
+		buf := bufio.NewWriterSize(w, DefaultCopyBufferSize)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := write(enc.Encode); err != nil {
+			return err
+		}
+		return buf.Flush()
+	})
+}
+
+// 打开文件并由 decode 解析，解析错误中带有文件名
+func (f *FS) decodeFile(file string, decode func(r io.Reader) error) error {
+	r, err := f.open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := decode(r); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
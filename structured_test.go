@@ -0,0 +1,84 @@
+package zfile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+type testConfig struct {
+	Name  string            `json:"name" yaml:"name" toml:"name"`
+	Port  int               `json:"port" yaml:"port" toml:"port"`
+	Tags  []string          `json:"tags" yaml:"tags" toml:"tags"`
+	Extra map[string]string `json:"extra,omitempty" yaml:"extra,omitempty" toml:"extra,omitempty"`
+}
+
+func TestStructuredFiles(t *testing.T) {
+	dir := t.TempDir()
+	conf := testConfig{Name: "app<1>", Port: 8080, Tags: []string{"a", "b"}, Extra: map[string]string{"k": "v"}}
+
+	for _, c := range []struct {
+		name  string
+		write func(string, interface{}) error
+		read  func(string, interface{}) error
+	}{
+		{"app.json", WriteJSON, ReadJSON},
+		{"app.yaml", WriteYAML, ReadYAML},
+		{"app.toml", WriteTOML, ReadTOML},
+	} {
+		name := filepath.Join(dir, "conf", c.name)
+		assert.Nil(t, c.write(name, conf), c.name)
+		var got testConfig
+		assert.Nil(t, c.read(name, &got), c.name)
+		assert.Equal(t, conf, got, c.name)
+	}
+	s, _ := ReadFile(filepath.Join(dir, "conf", "app.json"))
+	assert.Contains(t, s, "\n  \"name\": \"app<1>\"")
+
+	ReWriteFile(filepath.Join(dir, "bad.json"), []byte("{"))
+	err := ReadJSON(filepath.Join(dir, "bad.json"), &testConfig{})
+	assert.Contains(t, err.Error(), "bad.json")
+}
+
+func TestJSONLines(t *testing.T) {
+	fs := NewFS(memfs.New())
+	type event struct {
+		ID  int    `json:"id"`
+		Msg string `json:"msg"`
+	}
+	err := fs.WriteJSONLines("/data/events.jsonl", func(encode func(v interface{}) error) error {
+		for i := 1; i <= 3; i++ {
+			if err := encode(event{ID: i, Msg: "<m>"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	s, _ := fs.ReadFile("/data/events.jsonl")
+	assert.Equal(t, "{\"id\":1,\"msg\":\"<m>\"}\n{\"id\":2,\"msg\":\"<m>\"}\n{\"id\":3,\"msg\":\"<m>\"}\n", s)
+
+	var ids []int
+	err = fs.ReadJSONLines("/data/events.jsonl", func(decode func(v interface{}) error) error {
+		var ev event
+		if err := decode(&ev); err != nil {
+			return err
+		}
+		ids = append(ids, ev.ID)
+		if ev.ID == 2 {
+			return ErrStopLines
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, ids)
+
+	// 空行被跳过，解析错误带有行号
+	fs.ReWriteFile("/data/bad.jsonl", []byte("{\"id\":1}\n\n{bad}\n"))
+	err = fs.ReadJSONLines("/data/bad.jsonl", func(decode func(v interface{}) error) error {
+		return decode(&event{})
+	})
+	assert.Contains(t, err.Error(), "/data/bad.jsonl:3:")
+}
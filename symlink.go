@@ -0,0 +1,158 @@
+package zfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 遍历和复制目录时对符号链接的处理策略
+type SymlinkPolicy int
+
+const (
+	// 默认策略，保留链接本身：复制时在目标位置创建相同的链接，遍历时返回链接本身且不进入
+	SymlinkKeep SymlinkPolicy = iota
+	// 跟随链接，按链接指向的文件或目录处理，指向上级目录构成循环的链接不再进入
+	SymlinkFollow
+	// 忽略链接
+	SymlinkSkip
+	// 遇到链接时返回 ErrSymlinkNotAllowed
+	SymlinkError
+)
+
+var (
+	// 跟随链接时，链接指向正在遍历的上级目录
+	ErrSymlinkLoop = errors.New("symbolic link loop")
+	// 符号链接策略为 SymlinkError 时遇到了链接
+	ErrSymlinkNotAllowed = errors.New("symbolic link not allowed")
+)
+
+// 返回使用指定符号链接策略的 FS，影响 Walk、CopyFolder 及各文件列表函数
+// Default().WithSymlinkPolicy(SymlinkFollow).CopyFolderWithReport(src, target, nil)
+func (f *FS) WithSymlinkPolicy(policy SymlinkPolicy) *FS {
+	c := *f
+	c.symlinks = policy
+	return &c
+}
+
+// 返回当前的符号链接策略
+func (f *FS) SymlinkPolicy() SymlinkPolicy {
+	return f.symlinks
+}
+
+// 按符号链接策略处理 path，fi 为其 Lstat 的结果
+// 返回之后应使用的文件信息，skip 为 true 表示应忽略该路径
+func (f *FS) resolveEntry(path string, fi os.FileInfo) (info os.FileInfo, skip bool, err error) {
+	if !isSymlink(fi) {
+		return fi, false, nil
+	}
+	switch f.symlinks {
+	case SymlinkSkip:
+		return fi, true, nil
+	case SymlinkError:
+		return fi, false, &os.PathError{Op: "symlink", Path: path, Err: ErrSymlinkNotAllowed}
+	case SymlinkKeep:
+		return fi, false, nil
+	}
+	st, err := f.fs.Stat(path)
+	if err != nil {
+		// 悬空链接按链接本身返回，由调用方在打开时报错
+		if os.IsNotExist(err) {
+			return fi, false, nil
+		}
+		return fi, false, err
+	}
+	return st, false, nil
+}
+
+// 返回路径解析全部符号链接后的真实路径
+func (f *FS) realPath(name string) (string, error) {
+	if f.native {
+		real, err := filepath.EvalSymlinks(name)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(real)
+	}
+	return f.evalSymlinks(name, 0)
+}
+
+func (f *FS) evalSymlinks(name string, hops int) (string, error) {
+	sep := string(filepath.Separator)
+	resolved := sep
+	for _, comp := range strings.Split(filepath.Clean(sep+name), sep) {
+		if comp == "" {
+			continue
+		}
+		current := filepath.Join(resolved, comp)
+		fi, err := f.fs.Lstat(current)
+		if err != nil {
+			return "", err
+		}
+		if !isSymlink(fi) {
+			resolved = current
+			continue
+		}
+		if hops > 255 {
+			return "", &os.PathError{Op: "realpath", Path: name, Err: ErrSymlinkLoop}
+		}
+		target, err := f.fs.Readlink(current)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(resolved, target)
+		}
+		if resolved, err = f.evalSymlinks(target, hops+1); err != nil {
+			return "", err
+		}
+	}
+	return resolved, nil
+}
+
+// 记录正在遍历的目录的真实路径，用于发现链接构成的循环
+// 仅在 SymlinkFollow 策略下需要，其他策略不会进入链接，nil 即可
+type linkTracker struct {
+	fs    *FS
+	stack []string
+}
+
+func (f *FS) newLinkTracker() *linkTracker {
+	if f.symlinks != SymlinkFollow {
+		return nil
+	}
+	return &linkTracker{fs: f}
+}
+
+// 进入目录 dir，viaLink 表示 dir 本身是符号链接，返回 dir 的真实路径
+// 返回 false 表示 dir 指向正在遍历的上级目录，此时不应进入，也无需调用 leave
+func (t *linkTracker) enter(dir string, viaLink bool) (real string, entered bool, err error) {
+	if t == nil {
+		return dir, true, nil
+	}
+	if viaLink || len(t.stack) == 0 {
+		if real, err = t.fs.realPath(dir); err != nil {
+			return "", false, err
+		}
+	} else {
+		real = filepath.Join(t.stack[len(t.stack)-1], filepath.Base(dir))
+	}
+	for _, ancestor := range t.stack {
+		if ancestor == real {
+			return real, false, nil
+		}
+	}
+	t.stack = append(t.stack, real)
+	return real, true, nil
+}
+
+func (t *linkTracker) leave() {
+	if t != nil {
+		t.stack = t.stack[:len(t.stack)-1]
+	}
+}
+
+func isSymlink(fi os.FileInfo) bool {
+	return fi.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,63 @@
+package zfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestSymlinkPolicyList(t *testing.T) {
+	dir := t.TempDir()
+	ReWriteFile(filepath.Join(dir, "src", "a.txt"), []byte("a"))
+	ReWriteFile(filepath.Join(dir, "outside", "b.txt"), []byte("b"))
+	os.Symlink(filepath.Join(dir, "outside"), filepath.Join(dir, "src", "out"))
+	os.Symlink("..", filepath.Join(dir, "src", "loop"))
+
+	// 默认不跟随链接，与 filepath.Walk 的结果相同
+	files, err := GetFileListBySuffix(filepath.Join(dir, "src"), ".txt")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "src", "a.txt")}, files)
+	files, err = GetFileListBySuffix(filepath.Join(dir, "src"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "src", "a.txt"), filepath.Join(dir, "src", "loop"), filepath.Join(dir, "src", "out")}, files)
+
+	files, err = Default().WithSymlinkPolicy(SymlinkFollow).GetFileListBySuffix(filepath.Join(dir, "src"), ".txt")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "src", "a.txt"), filepath.Join(dir, "src", "loop", "outside", "b.txt"), filepath.Join(dir, "src", "out", "b.txt")}, files)
+
+	files, err = Default().WithSymlinkPolicy(SymlinkSkip).GetFileListBySuffix(filepath.Join(dir, "src"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "src", "a.txt")}, files)
+
+	_, err = Default().WithSymlinkPolicy(SymlinkError).GetFileListBySuffix(filepath.Join(dir, "src"), "")
+	assert.True(t, errors.Is(err, ErrSymlinkNotAllowed))
+}
+
+func TestSymlinkPolicyCopy(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a.txt", []byte("a"))
+	fs.ReWriteFile("/outside/b.txt", []byte("b"))
+	fs.fs.Symlink("/outside", "/src/out")
+	fs.fs.Symlink("/src", "/src/sub/loop")
+
+	report, err := fs.WithSymlinkPolicy(SymlinkFollow).CopyFolderWithReport("/src", "/follow", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Files)
+	assert.Equal(t, []string{"/src/sub/loop"}, report.Skipped)
+	content, _ := fs.ReadFile("/follow/out/b.txt")
+	assert.Equal(t, "b", content)
+
+	report, err = fs.CopyFolderWithReport("/src", "/keep", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Files)
+	assert.Equal(t, 2, report.Links)
+	link, _ := fs.fs.Readlink("/keep/out")
+	assert.Equal(t, "/outside", link)
+
+	_, err = fs.WithSymlinkPolicy(SymlinkError).CopyFolderWithReport("/src", "/error", nil)
+	assert.True(t, errors.Is(err, ErrSymlinkNotAllowed))
+}
@@ -0,0 +1,239 @@
+package zfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+// Sync 的可选项，nil 表示全部使用默认值
+type SyncOptions struct {
+	// 为 true 时比较文件内容的 sha256，否则比较大小和修改时间
+	Checksum bool
+	// 为 true 时删除目标目录中源目录不存在的文件和目录，被 Filter 排除的文件不删除
+	Delete bool
+	// 为 true 时只返回计划执行的操作，不修改目标目录
+	DryRun bool
+	// 过滤规则，nil 表示同步全部文件
+	Filter *Filter
+	// 需要额外保留的元数据，修改时间总是保留，以便下次按修改时间比较
+	Preserve Preserve
+	// 复制文件时读写缓冲区的大小，<=0 时使用 DefaultCopyBufferSize
+	BufferSize int
+}
+
+// 同步操作的类型
+type SyncAction int
+
+const (
+	SyncMkdir  SyncAction = iota // 创建目录
+	SyncCreate                   // 复制目标中不存在的文件
+	SyncUpdate                   // 覆盖内容不同的文件
+	SyncDelete                   // 删除目标中多余的文件或目录
+)
+
+func (a SyncAction) String() string {
+	switch a {
+	case SyncMkdir:
+		return "mkdir"
+	case SyncCreate:
+		return "create"
+	case SyncUpdate:
+		return "update"
+	case SyncDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("SyncAction(%d)", int(a))
+}
+
+// 一次同步操作，Path 为相对同步根目录的路径
+type SyncOp struct {
+	Action SyncAction
+	Path   string
+	Size   int64 // 复制的字节数，仅 SyncCreate 和 SyncUpdate 有意义
+}
+
+func (op SyncOp) String() string {
+	return op.Action.String() + " " + op.Path
+}
+
+// 增量同步目录，只复制大小、修改时间（或内容）不同的文件，返回已执行的操作
+// DryRun 时返回计划执行的操作；出错时返回出错前已执行的操作
+// Sync("./build", "/srv/app", &SyncOptions{Delete: true})
+func Sync(srcDir, dstDir string, opts *SyncOptions) ([]SyncOp, error) {
+	return defaultFS.Sync(srcDir, dstDir, opts)
+}
+
+// 增量同步目录，只复制大小、修改时间（或内容）不同的文件，返回已执行的操作
+func (f *FS) Sync(srcDir, dstDir string, opts *SyncOptions) ([]SyncOp, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	if !f.IsDir(srcDir) {
+		return nil, fmt.Errorf("given path does not exist: %s", srcDir)
+	}
+	s := &syncer{fs: f, opts: opts, srcDir: srcDir, dstDir: dstDir, seen: map[string]bool{}}
+	err := f.Walk(srcDir, s.syncEntry)
+	if err == nil && opts.Delete && f.IsDir(dstDir) {
+		// 目标目录中的链接按链接本身处理，避免删除链接指向的内容
+		err = f.WithSymlinkPolicy(SymlinkKeep).Walk(dstDir, s.deleteEntry)
+	}
+	return s.ops, err
+}
+
+type syncer struct {
+	fs     *FS
+	opts   *SyncOptions
+	srcDir string
+	dstDir string
+	seen   map[string]bool // 源目录中存在的相对路径
+	ops    []SyncOp
+}
+
+func (s *syncer) syncEntry(src string, fi os.FileInfo, err error) error {
+	if errors.Is(err, ErrSymlinkLoop) { // 忽略构成循环的链接
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(s.srcDir, src)
+	if err != nil {
+		return err
+	}
+	if rel != "." && !s.opts.Filter.Allow(filepath.ToSlash(rel), fi) {
+		if fi.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	s.seen[rel] = true
+	dst := s.fs.fs.Join(s.dstDir, rel)
+	dstInfo, err := s.fs.fs.Lstat(dst)
+	exists := err == nil
+	// 类型不同时先删除目标
+	if exists && (dstInfo.IsDir() != fi.IsDir() || isSymlink(dstInfo) != isSymlink(fi)) {
+		if err := s.apply(SyncOp{Action: SyncDelete, Path: rel}, func() error {
+			return util.RemoveAll(s.fs.fs, dst)
+		}); err != nil {
+			return err
+		}
+		exists = false
+	}
+
+	switch {
+	case fi.IsDir():
+		if exists {
+			return nil
+		}
+		return s.apply(SyncOp{Action: SyncMkdir, Path: rel}, func() error {
+			return s.fs.fs.MkdirAll(dst, os.ModePerm)
+		})
+	case isSymlink(fi) && s.fs.symlinks == SymlinkKeep: // 同步链接本身
+		if exists {
+			srcLink, err := s.fs.fs.Readlink(src)
+			if err != nil {
+				return err
+			}
+			if dstLink, err := s.fs.fs.Readlink(dst); err == nil && dstLink == srcLink {
+				return nil
+			}
+		}
+		return s.apply(s.op(exists, rel, 0), func() error {
+			return s.fs.copyLink(src, dst)
+		})
+	case !fi.Mode().IsRegular():
+		return nil
+	}
+
+	if exists {
+		same, err := s.same(src, dst, fi)
+		if err != nil || same {
+			return err
+		}
+	}
+	return s.apply(s.op(exists, rel, fi.Size()), func() error {
+		if _, err := s.fs.copyFile(src, dst, fi.Mode(), s.opts.BufferSize); err != nil {
+			return err
+		}
+		err := s.fs.preserveMetadata(src, dst, fi, s.opts.Preserve|PreserveTimes)
+		if err == billy.ErrNotSupported && s.opts.Preserve == 0 {
+			return nil
+		}
+		return err
+	})
+}
+
+func (s *syncer) deleteEntry(dst string, fi os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(s.dstDir, dst)
+	if err != nil {
+		return err
+	}
+	if rel == "." || s.seen[rel] || !s.opts.Filter.Allow(filepath.ToSlash(rel), fi) {
+		return nil
+	}
+	err = s.apply(SyncOp{Action: SyncDelete, Path: rel}, func() error {
+		return util.RemoveAll(s.fs.fs, dst)
+	})
+	if err == nil && fi.IsDir() {
+		return filepath.SkipDir
+	}
+	return err
+}
+
+func (s *syncer) op(exists bool, rel string, size int64) SyncOp {
+	if exists {
+		return SyncOp{Action: SyncUpdate, Path: rel, Size: size}
+	}
+	return SyncOp{Action: SyncCreate, Path: rel, Size: size}
+}
+
+// 执行操作并记录，DryRun 时只记录
+func (s *syncer) apply(op SyncOp, do func() error) error {
+	if !s.opts.DryRun {
+		if err := do(); err != nil {
+			return err
+		}
+	}
+	s.ops = append(s.ops, op)
+	return nil
+}
+
+// 判断目标文件与源文件是否相同
+func (s *syncer) same(src, dst string, fi os.FileInfo) (bool, error) {
+	dstSize, err := s.fs.FileSize(dst)
+	if err != nil {
+		return false, err
+	}
+	if dstSize != fi.Size() {
+		return false, nil
+	}
+	if s.opts.Checksum {
+		srcSum, err := s.fs.hashFile(src, HashSHA256)
+		if err != nil {
+			return false, err
+		}
+		dstSum, err := s.fs.hashFile(dst, HashSHA256)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(srcSum, dstSum), nil
+	}
+	srcTime, err := s.fs.FileModTime(src)
+	if err != nil {
+		return false, err
+	}
+	dstTime, err := s.fs.FileModTime(dst)
+	if err != nil {
+		return false, err
+	}
+	return srcTime == dstTime, nil
+}
@@ -0,0 +1,64 @@
+package zfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestSync(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	dst := filepath.Join(t.TempDir(), "dst")
+	ReWriteFile(filepath.Join(src, "a.txt"), []byte("a"))
+	ReWriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"))
+
+	ops, err := Sync(src, dst, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []SyncOp{
+		{Action: SyncMkdir, Path: "."},
+		{Action: SyncCreate, Path: "a.txt", Size: 1},
+		{Action: SyncMkdir, Path: "sub"},
+		{Action: SyncCreate, Path: filepath.Join("sub", "b.txt"), Size: 1},
+	}, ops)
+
+	ops, err = Sync(src, dst, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, ops)
+
+	ReWriteFile(filepath.Join(src, "a.txt"), []byte("aa"))
+	ReWriteFile(filepath.Join(dst, "old.txt"), []byte("old"))
+	ops, err = Sync(src, dst, &SyncOptions{Delete: true, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []SyncOp{{Action: SyncUpdate, Path: "a.txt", Size: 2}, {Action: SyncDelete, Path: "old.txt"}}, ops)
+	assert.True(t, CheckFileIsExist(filepath.Join(dst, "old.txt")))
+
+	ops, err = Sync(src, dst, &SyncOptions{Delete: true})
+	assert.Nil(t, err)
+	assert.Len(t, ops, 2)
+	assert.False(t, CheckFileIsExist(filepath.Join(dst, "old.txt")))
+	content, _ := ReadFile(filepath.Join(dst, "a.txt"))
+	assert.Equal(t, "aa", content)
+
+	// 大小相同、仅修改时间不同时按修改时间判断
+	mtime := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(src, "sub", "b.txt"), mtime, mtime)
+	ops, _ = Sync(src, dst, &SyncOptions{DryRun: true})
+	assert.Equal(t, []SyncOp{{Action: SyncUpdate, Path: filepath.Join("sub", "b.txt"), Size: 1}}, ops)
+	ops, _ = Sync(src, dst, &SyncOptions{DryRun: true, Checksum: true})
+	assert.Empty(t, ops)
+}
+
+func TestSyncChecksumMemory(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a.txt", []byte("hello"))
+	fs.ReWriteFile("/dst/a.txt", []byte("hello"))
+	fs.ReWriteFile("/dst/b.txt", []byte("world"))
+
+	ops, err := fs.Sync("/src", "/dst", &SyncOptions{Checksum: true, Delete: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []SyncOp{{Action: SyncDelete, Path: "b.txt"}}, ops)
+}
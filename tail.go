@@ -0,0 +1,310 @@
+package zfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// 从文件末尾向前查找时每次读取的字节数
+const tailChunkSize = 8 * 1024
+
+// Follow 每次检查时比较的文件开头的字节数，用于发现被截断后又写入了更多内容的文件
+const followHeadSize = 256
+
+// Follow 的可选项，nil 表示全部使用默认值
+type FollowOptions struct {
+	// 开始跟踪前先输出文件的最后几行，为 0 时只输出新写入的行
+	Lines int
+	// 检查文件变化的间隔，<=0 时为 250 毫秒
+	PollInterval time.Duration
+	// 单行最大字节数（不含换行符），超过时返回 ErrLineTooLong，<=0 时不限制
+	MaxLineLength int
+}
+
+// 读取文件的最后 n 行，从文件末尾向前读取，不会将整个文件读入内存
+// 返回的行不含行尾的 "\n" 或 "\r\n"，行数不足 n 时返回全部行
+// lines, err := Tail("logs/app.log", 100)
+func Tail(file string, n int) ([]string, error) {
+	return defaultFS.Tail(file, n)
+}
+
+// 读取文件的最后 n 行，从文件末尾向前读取，不会将整个文件读入内存
+func (f *FS) Tail(file string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	in, err := f.fs.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	size, err := f.FileSize(file)
+	if err != nil {
+		return nil, err
+	}
+	start, err := tailStart(in, size, n)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size-start)
+	if _, err := in.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			i = len(data) - 1
+		}
+		lines = append(lines, string(trimLineEnd(data[:i+1])))
+		data = data[i+1:]
+	}
+	return lines, nil
+}
+
+// 从末尾向前查找，返回最后 n 行开始的位置，文件末尾的换行符不算作空行
+func tailStart(r io.ReaderAt, size int64, n int) (int64, error) {
+	if n <= 0 {
+		return size, nil
+	}
+	buf := make([]byte, tailChunkSize)
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := r.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+			if n--; n == 0 {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// 跟踪文件新写入的行并回调 fn，类似 tail -F，直到 ctx 被取消
+// 文件被截断或被重命名轮转（如 RotateWriter、logrotate）后从新文件的开头继续读取
+// 截断按文件变短或开头 256 字节变化判断，logrotate copytruncate 后新写入的内容开头与原内容完全相同时无法发现
+// 文件不存在时等待其被创建；未以换行符结尾的行等到换行符写入后才输出，文件轮转时直接输出
+// fn 返回 ErrStopLines 或 ctx 被取消时返回 nil，fn 返回其他错误时返回该错误
+// Follow(ctx, "logs/app.log", func(line string) error { ... }, &FollowOptions{Lines: 10})
+func Follow(ctx context.Context, file string, fn func(line string) error, opts *FollowOptions) error {
+	return defaultFS.Follow(ctx, file, fn, opts)
+}
+
+// 跟踪文件新写入的行并回调 fn，类似 tail -F，直到 ctx 被取消
+func (f *FS) Follow(ctx context.Context, file string, fn func(line string) error, opts *FollowOptions) error {
+	fl := &follower{fs: f, name: file, fn: fn, buf: make([]byte, DefaultCopyBufferSize)}
+	if opts != nil {
+		fl.opts = *opts
+	}
+	interval := fl.opts.PollInterval
+	if interval <= 0 {
+		interval = 250 * time.Millisecond
+	}
+	if err := fl.open(true); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	defer fl.close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := fl.poll()
+		if err == ErrStopLines {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+type follower struct {
+	fs      *FS
+	name    string
+	fn      func(line string) error
+	opts    FollowOptions
+	file    billy.File
+	info    os.FileInfo // 打开的文件的信息，用于判断文件是否被替换
+	offset  int64
+	pending []byte // 尚未读到换行符的行
+	head    []byte // 文件开头最多 followHeadSize 字节，变化时表示文件被截断后重新写入
+	buf     []byte
+}
+
+// 打开文件，initial 为 true 时从最后 Lines 行开始读取，否则从头读取
+func (fl *follower) open(initial bool) error {
+	file, err := fl.fs.fs.Open(fl.name)
+	if err != nil {
+		return err
+	}
+	info, err := fl.fs.fs.Stat(fl.name)
+	if s, ok := file.(interface{ Stat() (os.FileInfo, error) }); ok {
+		info, err = s.Stat()
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+	fl.file, fl.info, fl.offset, fl.head = file, info, 0, fl.head[:0]
+	if initial {
+		if fl.offset, err = tailStart(file, info.Size(), fl.opts.Lines); err != nil {
+			return err
+		}
+		if _, err = file.Seek(fl.offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fl *follower) close() {
+	if fl.file != nil {
+		fl.file.Close()
+		fl.file = nil
+	}
+}
+
+// 读取新写入的内容，并检查文件是否被截断或轮转
+func (fl *follower) poll() error {
+	if fl.file == nil {
+		if err := fl.open(false); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := fl.checkRewritten(); err != nil {
+		return err
+	}
+	if err := fl.read(); err != nil {
+		return err
+	}
+	info, err := fl.fs.fs.Stat(fl.name)
+	if os.IsNotExist(err) { // 已被重命名，新文件尚未创建
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	replaced := !sameFile(fl.info, info)
+	if !replaced && info.Size() >= fl.offset {
+		return nil
+	}
+	if replaced { // 读完原文件中剩余的内容
+		if err := fl.read(); err != nil {
+			return err
+		}
+	}
+	if err := fl.flushPending(); err != nil {
+		return err
+	}
+	fl.close()
+	if err := fl.open(false); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return fl.poll()
+}
+
+// 文件开头的内容与上次不同时，文件已被截断（如 copytruncate）并写入了新内容，
+// 此时文件可能已比读取的位置更长，需要从头重新读取
+func (fl *follower) checkRewritten() error {
+	head := make([]byte, followHeadSize)
+	n, err := fl.file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	head = head[:n]
+	if !bytes.HasPrefix(head, fl.head) {
+		if err := fl.flushPending(); err != nil {
+			return err
+		}
+		if _, err := fl.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		fl.offset = 0
+	}
+	fl.head = append(fl.head[:0], head...)
+	return nil
+}
+
+// 输出未以换行符结尾的行
+func (fl *follower) flushPending() error {
+	if len(fl.pending) == 0 {
+		return nil
+	}
+	line := string(trimLineEnd(fl.pending))
+	fl.pending = fl.pending[:0]
+	return fl.fn(line)
+}
+
+// 读到文件末尾，输出读到的完整的行
+func (fl *follower) read() error {
+	for {
+		n, err := fl.file.Read(fl.buf)
+		fl.offset += int64(n)
+		if e := fl.emit(fl.buf[:n]); e != nil {
+			return e
+		}
+		if err == io.EOF || n == 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (fl *follower) emit(data []byte) error {
+	fl.pending = append(fl.pending, data...)
+	rest := fl.pending
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		line := trimLineEnd(rest[:i+1])
+		if fl.opts.MaxLineLength > 0 && len(line) > fl.opts.MaxLineLength {
+			return fmt.Errorf("%s: %w", fl.name, ErrLineTooLong)
+		}
+		if err := fl.fn(string(line)); err != nil {
+			return err
+		}
+		rest = rest[i+1:]
+	}
+	fl.pending = fl.pending[:copy(fl.pending, rest)]
+	// 预留 "\r\n" 的长度
+	if fl.opts.MaxLineLength > 0 && len(fl.pending) > fl.opts.MaxLineLength+2 {
+		return fmt.Errorf("%s: %w", fl.name, ErrLineTooLong)
+	}
+	return nil
+}
+
+// 判断两个文件信息是否属于同一文件，无法判断时（如内存文件系统）视为同一文件
+func sameFile(a, b os.FileInfo) bool {
+	if !os.SameFile(a, a) {
+		return true
+	}
+	return os.SameFile(a, b)
+}
@@ -0,0 +1,113 @@
+package zfile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestTail(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("a.log", []byte("1\r\n2\n\n3\n"))
+	lines, err := fs.Tail("a.log", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "3"}, lines)
+	lines, _ = fs.Tail("a.log", 10)
+	assert.Equal(t, []string{"1", "2", "", "3"}, lines)
+
+	// 跨越多个读取块，最后一行没有换行符
+	var content []string
+	for i := 0; i < 5000; i++ {
+		content = append(content, fmt.Sprintf("line %d", i))
+	}
+	fs.ReWriteFile("b.log", []byte(strings.Join(content, "\n")))
+	lines, _ = fs.Tail("b.log", 3000)
+	assert.Equal(t, content[2000:], lines)
+
+	_, err = fs.Tail("none.log", 1)
+	assert.NotNil(t, err)
+}
+
+// 收集 Follow 输出的行，直到收到 n 行
+func followLines(t *testing.T, fs *FS, name string, opts *FollowOptions, n int, actions func()) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lines := make(chan string, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- fs.Follow(ctx, name, func(line string) error {
+			lines <- line
+			return nil
+		}, opts)
+	}()
+	actions()
+	var got []string
+	for len(got) < n {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+		case <-ctx.Done():
+			t.Fatalf("timeout, got %v", got)
+		}
+	}
+	cancel()
+	assert.Nil(t, <-done)
+	return got
+}
+
+func TestFollowRotate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	ReWriteFile(name, []byte("old1\nold2\n"))
+	step := func() { time.Sleep(50 * time.Millisecond) }
+	lines := followLines(t, Default(), name, &FollowOptions{Lines: 1, PollInterval: 10 * time.Millisecond}, 6, func() {
+		step()
+		WriteAppend(name, []byte("new1\nparti"))
+		step()
+		WriteAppend(name, []byte("al\nlast"))
+		os.Rename(name, name+".1")
+		step()
+		WriteAppend(name, []byte("rotated\n"))
+		step()
+		// 截断后从头读取
+		ReWriteFile(name, []byte("trunc\n"))
+	})
+	assert.Equal(t, []string{"old2", "new1", "partial", "last", "rotated", "trunc"}, lines)
+}
+
+func TestFollowCopyTruncate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	ReWriteFile(name, []byte("first line\n"))
+	lines := followLines(t, Default(), name, &FollowOptions{Lines: 1, PollInterval: 10 * time.Millisecond}, 2, func() {
+		time.Sleep(50 * time.Millisecond)
+		// 截断后写入的内容比已读取的位置更长，按文件开头的变化发现
+		file, _ := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC, 0)
+		file.WriteString("second line is longer\n")
+		file.Close()
+	})
+	assert.Equal(t, []string{"first line", "second line is longer"}, lines)
+}
+
+func TestFollowWaitAndStop(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	var got []string
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		ReWriteFile(name, []byte("a\nb\nc\n"))
+	}()
+	err := Follow(context.Background(), name, func(line string) error {
+		got = append(got, line)
+		if line == "b" {
+			return ErrStopLines
+		}
+		return nil
+	}, &FollowOptions{PollInterval: 5 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+}
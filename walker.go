@@ -0,0 +1,202 @@
+package zfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// 由 WalkParallel 的回调函数返回，用于停止整个遍历，WalkParallel 返回 nil
+var ErrStopWalk = errors.New("stop walk")
+
+// WalkParallel 的可选项，nil 表示全部使用默认值
+type WalkOptions struct {
+	// 同时读取目录的协程数，<=0 时为 CPU 核数
+	Concurrency int
+	// 读取目录或目录项出错时回调，返回 nil 时跳过出错的路径继续遍历，返回其他错误时停止遍历并返回该错误
+	// 为 nil 时遇到错误即停止遍历并返回该错误
+	OnError func(path string, err error) error
+}
+
+// 并发遍历 root 及其下级目录，对每个文件和目录（包括 root）回调 fn
+// 使用 os.ReadDir 读取目录，除 root 外不对每个目录项做 stat，适合遍历文件数量巨大的目录
+// fn 会被多个协程同时调用，需自行保证并发安全；同一目录中的项按名称顺序回调，不同目录之间的顺序不确定
+// fn 对目录返回 filepath.SkipDir 时不再进入该目录，返回 ErrStopWalk 时停止遍历并返回 nil，返回其他错误时停止遍历并返回该错误
+// 停止后不再读取新的目录，但其他协程中已经开始的回调仍会执行完，fn 在停止后仍可能被调用少数几次
+// 符号链接不跟随，按链接本身回调，FS 的符号链接策略为 SymlinkSkip 时忽略链接，为 SymlinkError 时作为错误处理
+// WalkParallel("./", func(path string, d os.DirEntry) error { ... }, &WalkOptions{Concurrency: 16})
+func WalkParallel(root string, fn func(path string, d os.DirEntry) error, opts *WalkOptions) error {
+	return defaultFS.WalkParallel(root, fn, opts)
+}
+
+// 并发遍历 root 及其下级目录，对每个文件和目录（包括 root）回调 fn
+func (f *FS) WalkParallel(root string, fn func(path string, d os.DirEntry) error, opts *WalkOptions) error {
+	w := &parallelWalker{fs: f, fn: fn}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Concurrency <= 0 {
+		w.opts.Concurrency = runtime.NumCPU()
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	info, err := f.fs.Stat(root)
+	if err != nil {
+		return w.fail(root, err)
+	}
+	if w.visit(root, fileInfoEntry{info}) {
+		w.push(root)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+	if w.err == ErrStopWalk {
+		return nil
+	}
+	return w.err
+}
+
+type parallelWalker struct {
+	fs   *FS
+	fn   func(path string, d os.DirEntry) error
+	opts WalkOptions
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []string // 待读取的目录
+	pending int      // 待读取和正在读取的目录数
+	err     error    // 不为 nil 时停止遍历
+	done    int32    // 已停止时为 1，遍历目录项时无需加锁即可检查
+}
+
+func (w *parallelWalker) work() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && w.pending > 0 && w.err == nil {
+			w.cond.Wait()
+		}
+		if w.err != nil || w.pending == 0 {
+			w.mu.Unlock()
+			return
+		}
+		// 后进先出，接近深度优先，待读取的目录不会过多
+		dir := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.mu.Unlock()
+
+		w.readDir(dir)
+
+		w.mu.Lock()
+		if w.pending--; w.pending == 0 {
+			w.cond.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *parallelWalker) readDir(dir string) {
+	entries, err := w.fs.readDirEntries(dir)
+	if err != nil {
+		w.fail(dir, err)
+		return
+	}
+	for _, d := range entries {
+		if w.stopped() {
+			return
+		}
+		path := w.fs.fs.Join(dir, d.Name())
+		if d.Type()&os.ModeSymlink != 0 {
+			if w.fs.symlinks == SymlinkSkip {
+				continue
+			}
+			if w.fs.symlinks == SymlinkError {
+				w.fail(path, &os.PathError{Op: "walk", Path: path, Err: ErrSymlinkNotAllowed})
+				continue
+			}
+		}
+		if w.visit(path, d) {
+			w.push(path)
+		}
+	}
+}
+
+// 回调 fn，返回是否需要进入该目录
+func (w *parallelWalker) visit(path string, d os.DirEntry) bool {
+	err := w.fn(path, d)
+	if err == filepath.SkipDir {
+		return false
+	}
+	if err != nil {
+		w.stop(err)
+		return false
+	}
+	return d.IsDir()
+}
+
+func (w *parallelWalker) push(dir string) {
+	w.mu.Lock()
+	w.queue = append(w.queue, dir)
+	w.pending++
+	w.mu.Unlock()
+	w.cond.Signal()
+}
+
+// 交给 OnError 处理错误，返回处理后的错误
+func (w *parallelWalker) fail(path string, err error) error {
+	if w.opts.OnError != nil {
+		err = w.opts.OnError(path, err)
+	}
+	if err != nil {
+		w.stop(err)
+	}
+	return err
+}
+
+func (w *parallelWalker) stop(err error) {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	atomic.StoreInt32(&w.done, 1)
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+func (w *parallelWalker) stopped() bool {
+	return atomic.LoadInt32(&w.done) == 1
+}
+
+// 读取目录项，本地文件系统使用 os.ReadDir 避免对每一项做 stat
+func (f *FS) readDirEntries(dir string) ([]os.DirEntry, error) {
+	if name, ok := f.osPath(dir); ok {
+		return os.ReadDir(name)
+	}
+	infos, err := f.readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]os.DirEntry, len(infos))
+	for i, fi := range infos {
+		entries[i] = fileInfoEntry{fi}
+	}
+	return entries, nil
+}
+
+// 将 os.FileInfo 包装为 os.DirEntry
+type fileInfoEntry struct {
+	info os.FileInfo
+}
+
+func (e fileInfoEntry) Name() string               { return e.info.Name() }
+func (e fileInfoEntry) IsDir() bool                { return e.info.IsDir() }
+func (e fileInfoEntry) Type() os.FileMode          { return e.info.Mode().Type() }
+func (e fileInfoEntry) Info() (os.FileInfo, error) { return e.info, nil }
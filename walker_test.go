@@ -0,0 +1,160 @@
+package zfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestWalkParallel(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 5; j++ {
+			name := fmt.Sprintf("d%d/sub/f%d.txt", i, j)
+			ReWriteFile(filepath.Join(dir, name), []byte("x"))
+			want = append(want, name)
+		}
+	}
+	ReWriteFile(filepath.Join(dir, "skip/a.txt"), []byte("x"))
+
+	var mu sync.Mutex
+	var files []string
+	err := WalkParallel(dir, func(path string, d os.DirEntry) error {
+		if d.IsDir() && d.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			mu.Lock()
+			files = append(files, filepath.ToSlash(rel))
+			mu.Unlock()
+		}
+		return nil
+	}, &WalkOptions{Concurrency: 4})
+	assert.Nil(t, err)
+	sort.Strings(files)
+	sort.Strings(want)
+	assert.Equal(t, want, files)
+
+	// 停止遍历，单个协程时不会再回调之后的文件
+	var count int
+	err = WalkParallel(dir, func(path string, d os.DirEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if count++; count == 10 {
+			return ErrStopWalk
+		}
+		return nil
+	}, &WalkOptions{Concurrency: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, count)
+
+	myErr := errors.New("my error")
+	err = WalkParallel(dir, func(path string, d os.DirEntry) error {
+		if d.Name() == "f3.txt" {
+			return myErr
+		}
+		return nil
+	}, nil)
+	assert.Equal(t, myErr, err)
+}
+
+// 记录停止遍历之后读取目录的次数，每次读取稍作等待以便多个协程同时工作
+type stopRecorderFS struct {
+	billy.Filesystem
+	stopped    int32
+	readsAfter int32
+}
+
+func (s *stopRecorderFS) ReadDir(path string) ([]os.FileInfo, error) {
+	if atomic.LoadInt32(&s.stopped) == 1 {
+		atomic.AddInt32(&s.readsAfter, 1)
+	}
+	time.Sleep(time.Millisecond)
+	return s.Filesystem.ReadDir(path)
+}
+
+func TestWalkParallelStop(t *testing.T) {
+	rec := &stopRecorderFS{Filesystem: memfs.New()}
+	fs := NewFS(rec)
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			fs.ReWriteFile(fmt.Sprintf("/src/d%d/s%d/f.txt", i, j), []byte("x"))
+		}
+	}
+
+	// 多个协程时停止前已在执行的回调仍会完成，之后不再读取新的目录
+	const workers = 4
+	var count, callsAfter int32
+	err := fs.WalkParallel("/src", func(path string, d os.DirEntry) error {
+		if atomic.LoadInt32(&rec.stopped) == 1 {
+			atomic.AddInt32(&callsAfter, 1)
+			return nil
+		}
+		if !d.IsDir() && atomic.AddInt32(&count, 1) == 10 {
+			atomic.StoreInt32(&rec.stopped, 1)
+			return ErrStopWalk
+		}
+		return nil
+	}, &WalkOptions{Concurrency: workers})
+	assert.Nil(t, err)
+	assert.True(t, atomic.LoadInt32(&count) >= 10)
+	// 每个协程最多还有一次正在进行的读取和回调，各留一倍余量
+	assert.True(t, atomic.LoadInt32(&rec.readsAfter) <= 2*workers, "reads after stop: %d", rec.readsAfter)
+	assert.True(t, atomic.LoadInt32(&callsAfter) <= 2*workers, "callbacks after stop: %d", callsAfter)
+
+	// WalkParallel 返回后不再有协程读取目录
+	reads := atomic.LoadInt32(&rec.readsAfter)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, reads, atomic.LoadInt32(&rec.readsAfter))
+}
+
+func TestWalkParallelOnError(t *testing.T) {
+	fs := NewFS(memfs.New())
+	fs.ReWriteFile("/src/a.txt", []byte("a"))
+	fs.ReWriteFile("/src/sub/b.txt", []byte("b"))
+	fs.fs.Symlink("/src/sub", "/src/link")
+
+	var mu sync.Mutex
+	var paths, failed []string
+	walk := func(f *FS, onError func(path string, err error) error) error {
+		paths, failed = nil, nil
+		return f.WalkParallel("/src", func(path string, d os.DirEntry) error {
+			mu.Lock()
+			paths = append(paths, path)
+			mu.Unlock()
+			return nil
+		}, &WalkOptions{OnError: onError})
+	}
+
+	assert.Nil(t, walk(fs, nil))
+	sort.Strings(paths)
+	assert.Equal(t, []string{"/src", "/src/a.txt", "/src/link", "/src/sub", "/src/sub/b.txt"}, paths)
+
+	err := walk(fs.WithSymlinkPolicy(SymlinkError), func(path string, err error) error {
+		mu.Lock()
+		failed = append(failed, path)
+		mu.Unlock()
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/src/link"}, failed)
+	assert.Len(t, paths, 4)
+
+	err = walk(fs.WithSymlinkPolicy(SymlinkError), nil)
+	assert.True(t, errors.Is(err, ErrSymlinkNotAllowed))
+
+	err = walk(NewFS(memfs.New()), nil)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,400 @@
+package zfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 当前平台或文件系统不支持 inotify 等原生的监视方式，改为轮询
+var errNativeWatchUnsupported = errors.New("native watch unsupported")
+
+// 文件变化的类型，防抖合并后可能同时包含多种
+type WatchOp uint32
+
+const (
+	WatchCreate WatchOp = 1 << iota // 创建，或从其他位置移入
+	WatchWrite                      // 内容或修改时间、权限等属性被修改
+	WatchRemove                     // 被删除
+	WatchRename                     // 被重命名或移出，新路径（如仍在监视范围内）另有 WatchCreate 事件
+)
+
+func (op WatchOp) String() string {
+	var names []string
+	for _, n := range []struct {
+		op   WatchOp
+		name string
+	}{{WatchCreate, "CREATE"}, {WatchWrite, "WRITE"}, {WatchRemove, "REMOVE"}, {WatchRename, "RENAME"}} {
+		if op&n.op != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("WatchOp(%d)", uint32(op))
+	}
+	return strings.Join(names, "|")
+}
+
+// 一次文件变化
+type WatchEvent struct {
+	Path string
+	Op   WatchOp
+}
+
+func (e WatchEvent) String() string {
+	return e.Op.String() + " " + e.Path
+}
+
+// Watch 的可选项，nil 表示全部使用默认值
+type WatchOptions struct {
+	// 为 true 时监视下级目录，包括之后新建的目录
+	Recursive bool
+	// 只报告文件名以这些后缀结尾的文件和目录，不区分大小写，为空时不限制
+	Suffixes []string
+	// 只报告文件名匹配该通配符的文件和目录，语法同 path.Match，为空时不限制
+	Glob string
+	// 同一路径在该时间内的多次变化合并为一个事件，在最后一次变化之后该时间才报告，<=0 时不合并
+	Debounce time.Duration
+	// 轮询的间隔，<=0 时为 1 秒
+	PollInterval time.Duration
+	// 为 true 时总是轮询，不使用 inotify（如网络文件系统上 inotify 不可用）
+	ForcePolling bool
+}
+
+// 监视文件或目录的变化，使用完毕后需调用 Close
+// Linux 本地文件系统使用 inotify，其他情况按 PollInterval 轮询比较文件的大小和修改时间
+type Watcher struct {
+	// 文件变化事件，Close 后关闭
+	Events <-chan WatchEvent
+	// 监视过程中的错误，如 inotify 队列溢出，不影响之后的监视，未及时读取的错误会被丢弃
+	Errors <-chan error
+
+	fs       *FS
+	root     string // 监视的目录
+	name     string // 监视单个文件时为文件名
+	opts     WatchOptions
+	suffixes []string
+	events   chan WatchEvent
+	errors   chan error
+	raw      chan WatchEvent // 过滤和防抖之前的事件
+	done     chan struct{}
+	backend  io.Closer
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// 监视文件或目录的变化，path 为文件时监视该文件，为目录时监视其中的文件和目录
+// w, _ := Watch("./conf", &WatchOptions{Recursive: true, Suffixes: []string{".yaml"}, Debounce: 100 * time.Millisecond})
+// defer w.Close()
+// for ev := range w.Events { ... }
+func Watch(path string, opts *WatchOptions) (*Watcher, error) {
+	return defaultFS.Watch(path, opts)
+}
+
+// 监视文件或目录的变化，非本地文件系统总是轮询
+func (f *FS) Watch(name string, opts *WatchOptions) (*Watcher, error) {
+	info, err := f.fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fs:     f,
+		root:   name,
+		events: make(chan WatchEvent),
+		errors: make(chan error, 8),
+		raw:    make(chan WatchEvent, 64),
+		done:   make(chan struct{}),
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Glob != "" {
+		if _, err := path.Match(w.opts.Glob, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, suffix := range w.opts.Suffixes {
+		w.suffixes = append(w.suffixes, strings.ToUpper(suffix)) //忽略后缀匹配的大小写
+	}
+	if !info.IsDir() {
+		// 监视所在目录，文件被编辑器以重命名的方式替换后仍能继续监视
+		w.root, w.name = filepath.Dir(name), filepath.Base(name)
+		w.opts.Recursive = false
+	}
+	w.Events, w.Errors = w.events, w.errors
+
+	var run func()
+	if name, ok := f.osPath(w.root); ok && !w.opts.ForcePolling {
+		run, w.backend, err = newNativeWatcher(w, name)
+	}
+	if run == nil {
+		if err != nil && !errors.Is(err, errNativeWatchUnsupported) {
+			return nil, err
+		}
+		p := newPoller(w)
+		if err := p.init(); err != nil {
+			return nil, err
+		}
+		run, w.backend = p.run, p
+	}
+	w.wg.Add(2)
+	go func() {
+		defer w.wg.Done()
+		defer close(w.raw)
+		run()
+	}()
+	go func() {
+		defer w.wg.Done()
+		w.dispatch()
+	}()
+	return w, nil
+}
+
+// 停止监视，关闭 Events
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.backend.Close()
+		w.wg.Wait()
+	})
+	return err
+}
+
+// 由监视的后端调用，发送未经过滤的事件，返回 false 表示监视已停止
+func (w *Watcher) emit(ev WatchEvent) bool {
+	if w.name != "" && filepath.Base(ev.Path) != w.name {
+		return true
+	}
+	select {
+	case w.raw <- ev:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// 发送错误，Errors 的缓冲已满时丢弃，避免未读取 Errors 时阻塞监视
+func (w *Watcher) fail(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+// 判断事件是否符合后缀和通配符
+func (w *Watcher) match(name string) bool {
+	name = filepath.Base(name)
+	if w.opts.Glob != "" {
+		if ok, _ := path.Match(w.opts.Glob, name); !ok {
+			return false
+		}
+	}
+	if len(w.suffixes) == 0 {
+		return true
+	}
+	upper := strings.ToUpper(name)
+	for _, suffix := range w.suffixes {
+		if strings.HasSuffix(upper, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 过滤事件，并按路径防抖后发送到 Events
+func (w *Watcher) dispatch() {
+	defer close(w.events)
+	var order []string // 等待发送的路径，按第一次变化的顺序
+	pending := map[string]WatchOp{}
+	deadline := map[string]time.Time{}
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	// 发送已到期的事件，all 为 true 时发送全部事件
+	flush := func(all bool) bool {
+		now := time.Now()
+		next := time.Time{}
+		rest := order[:0]
+		for _, p := range order {
+			if !all && deadline[p].After(now) {
+				rest = append(rest, p)
+				if next.IsZero() || deadline[p].Before(next) {
+					next = deadline[p]
+				}
+				continue
+			}
+			select {
+			case w.events <- WatchEvent{Path: p, Op: pending[p]}:
+			case <-w.done:
+				return false
+			}
+			delete(pending, p)
+			delete(deadline, p)
+		}
+		order = rest
+		if !next.IsZero() {
+			timer.Reset(next.Sub(now))
+		}
+		return true
+	}
+	for {
+		select {
+		case ev, ok := <-w.raw:
+			if !ok {
+				flush(true)
+				return
+			}
+			if !w.match(ev.Path) {
+				continue
+			}
+			if w.opts.Debounce <= 0 {
+				select {
+				case w.events <- ev:
+				case <-w.done:
+					return
+				}
+				continue
+			}
+			if _, ok := pending[ev.Path]; !ok {
+				order = append(order, ev.Path)
+			}
+			pending[ev.Path] |= ev.Op
+			deadline[ev.Path] = time.Now().Add(w.opts.Debounce)
+			if len(order) == 1 {
+				timer.Reset(w.opts.Debounce)
+			}
+		case <-timer.C:
+			if !flush(false) {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// 轮询比较文件的大小和修改时间
+type poller struct {
+	w     *Watcher
+	state map[string]os.FileInfo
+	stop  chan struct{}
+}
+
+func newPoller(w *Watcher) *poller {
+	return &poller{w: w, stop: make(chan struct{})}
+}
+
+func (p *poller) init() (err error) {
+	p.state, err = p.scan()
+	return err
+}
+
+func (p *poller) run() {
+	interval := p.w.opts.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		state, err := p.scan()
+		if err != nil {
+			p.w.fail(err)
+			continue
+		}
+		if !p.compare(p.state, state) {
+			return
+		}
+		p.state = state
+	}
+}
+
+func (p *poller) Close() error {
+	close(p.stop)
+	return nil
+}
+
+// 返回监视范围内所有路径的文件信息，监视的目录不存在时返回空
+func (p *poller) scan() (map[string]os.FileInfo, error) {
+	f, root := p.w.fs, p.w.root
+	state := make(map[string]os.FileInfo)
+	if !p.w.opts.Recursive {
+		infos, err := f.readDir(root)
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		for _, fi := range infos {
+			state[f.fs.Join(root, fi.Name())] = fi
+		}
+		return state, err
+	}
+	err := f.WithSymlinkPolicy(SymlinkKeep).Walk(root, func(name string, fi os.FileInfo, err error) error {
+		if os.IsNotExist(err) { // 遍历过程中被删除
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if name != root {
+			state[name] = fi
+		}
+		return nil
+	})
+	return state, err
+}
+
+// 比较两次扫描的结果并发送事件，返回 false 表示监视已停止
+func (p *poller) compare(old, cur map[string]os.FileInfo) bool {
+	var removed, created, written []string
+	for name, fi := range old {
+		if now, ok := cur[name]; !ok {
+			removed = append(removed, name)
+		} else if !fi.IsDir() && (now.Size() != fi.Size() || !now.ModTime().Equal(fi.ModTime())) {
+			written = append(written, name)
+		}
+	}
+	for name := range cur {
+		if _, ok := old[name]; !ok {
+			created = append(created, name)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(created)
+	sort.Strings(written)
+	var events []WatchEvent
+	for _, name := range removed {
+		op := WatchRemove
+		// 本地文件系统上同一文件出现在新路径，视为重命名
+		for _, newName := range created {
+			if os.SameFile(old[name], cur[newName]) {
+				op = WatchRename
+				break
+			}
+		}
+		events = append(events, WatchEvent{Path: name, Op: op})
+	}
+	for _, name := range created {
+		events = append(events, WatchEvent{Path: name, Op: WatchCreate})
+	}
+	for _, name := range written {
+		events = append(events, WatchEvent{Path: name, Op: WatchWrite})
+	}
+	for _, ev := range events {
+		if !p.w.emit(ev) {
+			return false
+		}
+	}
+	return true
+}
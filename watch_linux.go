@@ -0,0 +1,198 @@
+//go:build linux
+// +build linux
+
+package zfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// 使用 inotify 监视本地目录，root 为目录在本地文件系统中的路径
+type inotifyWatcher struct {
+	w    *Watcher
+	root string
+	fd   int
+	file *os.File // 用于读取事件，Fd 会将描述符改为阻塞模式，其他系统调用使用 fd
+
+	mu    sync.Mutex
+	paths map[int32]string // watch descriptor 到目录的映射
+	wds   map[string]int32
+}
+
+func newNativeWatcher(w *Watcher, root string) (func(), io.Closer, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errNativeWatchUnsupported, err)
+	}
+	// 非阻塞的描述符由 runtime 的 poller 管理，Close 时会唤醒阻塞中的 Read
+	iw := &inotifyWatcher{
+		w:     w,
+		root:  root,
+		fd:    fd,
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		paths: map[int32]string{},
+		wds:   map[string]int32{},
+	}
+	if err := iw.addDir(root, false); err != nil {
+		iw.file.Close()
+		// 超出 inotify 数量限制时改为轮询
+		if errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE) {
+			err = fmt.Errorf("%w: %v", errNativeWatchUnsupported, err)
+		}
+		return nil, nil, err
+	}
+	return iw.run, iw.file, nil
+}
+
+// 监视目录，递归监视时同时监视下级目录；created 为 true 时为目录中已有的内容发送创建事件
+// 新建的目录被监视之前，其中可能已经创建了文件
+func (iw *inotifyWatcher) addDir(dir string, created bool) error {
+	if err := iw.add(dir); err != nil {
+		return err
+	}
+	if !iw.w.opts.Recursive {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, d := range entries {
+		name := filepath.Join(dir, d.Name())
+		if created && !iw.w.emit(WatchEvent{Path: iw.eventPath(name), Op: WatchCreate}) {
+			return nil
+		}
+		if d.IsDir() {
+			if err := iw.addDir(name, created); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (iw *inotifyWatcher) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(iw.fd, dir, inotifyMask|syscall.IN_ONLYDIR|syscall.IN_DONT_FOLLOW)
+	if err != nil {
+		if err == syscall.ENOENT {
+			return nil // 监视前已被删除
+		}
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	iw.mu.Lock()
+	iw.paths[int32(wd)] = dir
+	iw.wds[dir] = int32(wd)
+	iw.mu.Unlock()
+	return nil
+}
+
+// 移除目录及其下级目录的监视，目录被移出时内核不会自动移除
+func (iw *inotifyWatcher) remove(dir string) {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	prefix := dir + string(filepath.Separator)
+	for path, wd := range iw.wds {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			syscall.InotifyRmWatch(iw.fd, uint32(wd))
+			delete(iw.wds, path)
+			delete(iw.paths, wd)
+		}
+	}
+}
+
+// 将本地路径转换为 FS 中的路径
+func (iw *inotifyWatcher) eventPath(name string) string {
+	rel, err := filepath.Rel(iw.root, name)
+	if err != nil {
+		return name
+	}
+	return iw.w.fs.fs.Join(iw.w.root, rel)
+}
+
+func (iw *inotifyWatcher) run() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := iw.file.Read(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrClosed) {
+				return
+			}
+			iw.w.fail(err)
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+			offset += syscall.SizeofInotifyEvent + int(raw.Len)
+			if !iw.handle(raw.Wd, raw.Mask, string(bytes.TrimRight(nameBytes, "\x00"))) {
+				return
+			}
+		}
+	}
+}
+
+// 处理一个 inotify 事件，返回 false 表示监视已停止
+func (iw *inotifyWatcher) handle(wd int32, mask uint32, name string) bool {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		iw.w.fail(errors.New("inotify queue overflow, some events were lost"))
+		return true
+	}
+	iw.mu.Lock()
+	dir, ok := iw.paths[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(iw.paths, wd)
+		if iw.wds[dir] == wd {
+			delete(iw.wds, dir)
+		}
+	}
+	iw.mu.Unlock()
+	if !ok {
+		return true
+	}
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	} else if dir != iw.root {
+		// 下级目录自身的删除和移动已由上级目录的事件报告
+		return true
+	}
+	isDir := mask&syscall.IN_ISDIR != 0
+	var op WatchOp
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		op = WatchCreate
+		if isDir && iw.w.opts.Recursive {
+			if err := iw.addDir(path, true); err != nil {
+				iw.w.fail(err)
+			}
+		}
+	case mask&(syscall.IN_MODIFY|syscall.IN_ATTRIB) != 0:
+		// 与轮询一致，修改时间等属性的变化按写入报告
+		op = WatchWrite
+	case mask&(syscall.IN_DELETE|syscall.IN_DELETE_SELF) != 0:
+		op = WatchRemove
+	case mask&(syscall.IN_MOVED_FROM|syscall.IN_MOVE_SELF) != 0:
+		op = WatchRename
+		if isDir {
+			iw.remove(path)
+		}
+	default:
+		return true
+	}
+	return iw.w.emit(WatchEvent{Path: iw.eventPath(path), Op: op})
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package zfile
+
+import (
+	"io"
+)
+
+// 非 linux 平台暂不支持原生的监视方式，改为轮询
+func newNativeWatcher(w *Watcher, root string) (func(), io.Closer, error) {
+	return nil, nil, errNativeWatchUnsupported
+}
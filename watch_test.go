@@ -0,0 +1,137 @@
+package zfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 等待满足条件的事件，超时返回 false
+func waitEvent(t *testing.T, w *Watcher, cond func(WatchEvent) bool) bool {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return false
+			}
+			if cond(ev) {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func appendString(name, s string) {
+	f, _ := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(s)
+	f.Close()
+}
+
+func isEvent(name string, op WatchOp) func(WatchEvent) bool {
+	return func(ev WatchEvent) bool {
+		return ev.Path == name && ev.Op&op != 0
+	}
+}
+
+func TestWatchPolling(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	ReWriteFile(a, []byte("a"))
+	w, err := Watch(dir, &WatchOptions{ForcePolling: true, PollInterval: 20 * time.Millisecond})
+	assert.Nil(t, err)
+	defer w.Close()
+
+	b := filepath.Join(dir, "b.txt")
+	ReWriteFile(b, []byte("b"))
+	assert.True(t, waitEvent(t, w, isEvent(b, WatchCreate)))
+	appendString(a, "more")
+	assert.True(t, waitEvent(t, w, isEvent(a, WatchWrite)))
+	c := filepath.Join(dir, "c.txt")
+	os.Rename(b, c)
+	assert.True(t, waitEvent(t, w, isEvent(b, WatchRename)))
+	assert.True(t, waitEvent(t, w, isEvent(c, WatchCreate)))
+	os.Remove(c)
+	assert.True(t, waitEvent(t, w, isEvent(c, WatchRemove)))
+}
+
+func TestWatchChtimes(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		dir := t.TempDir()
+		a := filepath.Join(dir, "a.txt")
+		ReWriteFile(a, []byte("a"))
+		w, err := Watch(dir, &WatchOptions{ForcePolling: polling, PollInterval: 20 * time.Millisecond})
+		assert.Nil(t, err)
+
+		// 只修改时间，内容和大小不变
+		mtime := time.Now().Add(-time.Hour)
+		os.Chtimes(a, mtime, mtime)
+		assert.True(t, waitEvent(t, w, isEvent(a, WatchWrite)), "polling: %v", polling)
+		w.Close()
+	}
+}
+
+func TestWatchRecursive(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		dir := t.TempDir()
+		w, err := Watch(dir, &WatchOptions{
+			Recursive:    true,
+			Suffixes:     []string{".yaml"},
+			Debounce:     50 * time.Millisecond,
+			PollInterval: 20 * time.Millisecond,
+			ForcePolling: polling,
+		})
+		assert.Nil(t, err)
+
+		// 新建的下级目录同样被监视，不符合后缀的文件被忽略
+		ReWriteFile(filepath.Join(dir, "sub", "skip.txt"), []byte("x"))
+		conf := filepath.Join(dir, "sub", "deep", "app.yaml")
+		ReWriteFile(conf, []byte("a: 1"))
+		assert.True(t, waitEvent(t, w, func(ev WatchEvent) bool {
+			assert.NotEqual(t, ".txt", filepath.Ext(ev.Path))
+			return ev.Path == conf && ev.Op&WatchCreate != 0
+		}), "polling=%v", polling)
+		time.Sleep(100 * time.Millisecond)
+		appendString(conf, "b: 2")
+		assert.True(t, waitEvent(t, w, isEvent(conf, WatchWrite)), "polling=%v", polling)
+
+		assert.Nil(t, w.Close())
+		_, ok := <-w.Events
+		assert.False(t, ok)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "app.conf")
+	other := filepath.Join(dir, "other.conf")
+	ReWriteFile(conf, []byte("a"))
+	w, err := Watch(conf, &WatchOptions{Debounce: 50 * time.Millisecond})
+	assert.Nil(t, err)
+	defer w.Close()
+
+	// 同一目录中的其他文件被忽略，原子写入替换文件后仍能收到事件
+	ReWriteFile(other, []byte("b"))
+	assert.Nil(t, ReWriteFileAtomic(conf, []byte("new")))
+	assert.True(t, waitEvent(t, w, func(ev WatchEvent) bool {
+		assert.Equal(t, conf, ev.Path)
+		return ev.Op&WatchCreate != 0
+	}))
+
+	_, err = Watch(filepath.Join(dir, "none"), nil)
+	assert.NotNil(t, err)
+	_, err = Watch(dir, &WatchOptions{Glob: "[a"})
+	assert.NotNil(t, err)
+}
+
+func TestWatchOpString(t *testing.T) {
+	assert.Equal(t, "CREATE|WRITE", (WatchCreate | WatchWrite).String())
+	assert.Equal(t, "RENAME /a", WatchEvent{Path: "/a", Op: WatchRename}.String())
+	assert.Equal(t, "WatchOp(0)", WatchOp(0).String())
+}
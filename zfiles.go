@@ -4,12 +4,8 @@ package zfile
 // https://blog.csdn.net/robertkun/article/details/78744464
 // https://www.cnblogs.com/zheng-chuang/p/6193090.html
 import (
-	"bufio"
 	"fmt"
 	"github.com/kuaileniu/zstring"
-	"go.uber.org/zap"
-	"io"
-	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
@@ -44,119 +40,46 @@ func GoToPath(relitiveOrAbsPath string) (absPath string, err error) {
 // https://github.com/yudeguang/file/blob/master/file.go
 // 检察文件是否允许读
 func AllowRead(path string) bool {
-	_, err := os.OpenFile(path, os.O_RDONLY, 0666)
-	return err == nil
+	return defaultFS.AllowRead(path)
 }
 
 // 检察文件是否允许写
 func AllowWrite(path string) bool {
-	_, err := os.OpenFile(path, os.O_WRONLY, 0666)
-	return err == nil
+	return defaultFS.AllowWrite(path)
 }
 
 // 打开指定文件，并从指定位置写入数据
 func WriteAt(path string, b []byte, off int64) error {
-	file, err := os.OpenFile(path, os.O_WRONLY, 0666)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	_, err = file.WriteAt(b, off)
-	return err
+	return defaultFS.WriteAt(path, b, off)
 }
 
-// 打开指定文件,并在文件末尾写入数据
-func WriteAppend(path string, b []byte) error {
-	file, err := os.OpenFile(path, os.O_APPEND, 0666)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	_, err = file.Write(b)
-	return err
+// 打开指定文件,并在文件末尾写入数据，文件或所在目录不存在时自动创建
+// syncWrite 为 true 时写入后 fsync
+func WriteAppend(path string, b []byte, syncWrite ...bool) error {
+	return defaultFS.WriteAppend(path, b, syncWrite...)
 }
 
 // 新建文件，若有重名文件则删除重建
 // ("../build/a.xml") 或  ("./build/a.xml") 或  ("build/a.xml")
 func ReCreateFile(relitivePathAndFileName string) (absPathFileName string, err error) {
-	file, err := os.Open(relitivePathAndFileName)
-	defer file.Close()
-	file, err = os.Create(relitivePathAndFileName)
-	if err != nil {
-		err = os.MkdirAll(filepath.Dir(relitivePathAndFileName), 0777)
-		if err != nil {
-			return "", err
-		}
-		file, err = os.Create(relitivePathAndFileName)
-		if err != nil {
-			return "", err
-		}
-	}
-	absPathFileName, _ = AbsPath(relitivePathAndFileName)
-	return absPathFileName, nil
+	return defaultFS.ReCreateFile(relitivePathAndFileName)
 }
 
 // 覆盖已有内容重新写入
 // 如果已经存在则打开文件，如果之前不存在则创建文件，然后覆盖已有内容重新写入
 // ("../build/a.xml",[]byte(""))
 func ReWriteFile(relitivePathAndFileName string, b []byte) error {
-	file, err := os.Open(relitivePathAndFileName)
-	defer file.Close()
-	file, err = os.Create(relitivePathAndFileName)
-	if err != nil {
-		err = os.MkdirAll(filepath.Dir(relitivePathAndFileName), 0777)
-		if err != nil {
-			return err
-		}
-		file, err = os.Create(relitivePathAndFileName)
-		if err != nil {
-			return err
-		}
-	}
-	_, err = file.Write(b)
-	return err
+	return defaultFS.ReWriteFile(relitivePathAndFileName, b)
 }
 
 // 复制文件，目标文件所在目录不存在，则创建目录后再复制
 // Copy(`d:\test\hello.txt`,`c:\test\hello.txt`)
 func Copy(dstFileName, srcFileName string) (w int64, err error) {
-	//打开源文件
-	srcFile, err := os.Open(srcFileName)
-	if err != nil {
-		return 0, err
-	}
-	defer srcFile.Close()
-	// 创建新的文件作为目标文件
-	dstFile, err := os.Create(dstFileName)
-	if err != nil {
-		//如果出错，很可能是目标目录不存在，需要先创建目标目录
-		err = os.MkdirAll(filepath.Dir(dstFileName), 0666)
-		if err != nil {
-			return 0, err
-		}
-		//再次尝试创建
-		dstFile, err = os.Create(dstFileName)
-		if err != nil {
-			return 0, err
-		}
-	}
-	defer dstFile.Close()
-	//通过bufio实现对大文件复制的自动支持
-	dst := bufio.NewWriter(dstFile)
-	defer dst.Flush()
-	src := bufio.NewReader(srcFile)
-	w, err = io.Copy(dst, src)
-	if err != nil {
-		return 0, err
-	}
-	return w, err
+	return defaultFS.Copy(dstFileName, srcFileName)
 }
 
 func CreateFolder(dir string) (err error) {
-	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-		return err
-	}
-	return nil
+	return defaultFS.CreateFolder(dir)
 }
 
 // 根据相对路径获取绝对路径
@@ -169,39 +92,22 @@ func AbsPath(reletivePath string) (absPath string, err error) {
 // file 可为绝对路径，可为相对路径
 // return 文本文件内容
 func ReadFile(file string) (context string, err error) {
-	data, e := ioutil.ReadFile(file)
-	// data,e := os.ReadFile(absolutePath)
-	if e != nil {
-		return "", e
-	}
-	return string(data), nil
+	return defaultFS.ReadFile(file)
 }
 
-
 // 读取文本文件中内容为字节
 // file 可为绝对路径，可为相对路径
 // return 文本文件内容
-func ReadFileByte(filePath string) ([]byte,error) {
-	data,e := os.ReadFile(filePath)
-	if e != nil {
-		return nil, e
-	}
-	return data, nil
+func ReadFileByte(filePath string) ([]byte, error) {
+	return defaultFS.ReadFileByte(filePath)
 }
 
-// 读取文本文件中的行
-// file 可为绝对路径，可为相对路径
+// 读取文本文件中的行，行尾的 "\r\n" 或 "\n" 会被去掉，文件末尾的换行符不产生空行
+// 按原始字节读取，不转换编码；GBK、UTF-16 等编码的文件请使用 OpenLineReader 并指定 LineOptions.Encoding
+// file 可为绝对路径，可为相对路径；大文件请使用 ReadLines 或 OpenLineReader 逐行处理
 // return 文件中的行列表
 func ReadFileLines(file string) (lines []string, err error) {
-	data, e := ioutil.ReadFile(file)
-	if e != nil {
-		return nil, e
-	}
-	for _, line := range strings.Split(string(data), "\n") {
-		// fmt.Println(line)
-		lines = append(lines, line)
-	}
-	return
+	return defaultFS.ReadFileLines(file)
 }
 
 // 按源路径的层级不变copy
@@ -210,32 +116,7 @@ func ReadFileLines(file string) (lines []string, err error) {
 // File.Name是路径信息，FileInfo.Name是文件名
 
 func CopyFolder(srcAbsDir string, targetAbsDir string, copySubfolder ...bool) {
-	files, err := ioutil.ReadDir(srcAbsDir)
-	if err != nil {
-		zap.L().Error("读取源文件夹异常", zap.String("srcAbsDir", srcAbsDir), zap.Error(err))
-		return
-	}
-	if err := os.MkdirAll(targetAbsDir, os.ModePerm); err != nil {
-		zap.L().Error("创建目标文件夹异常", zap.String("srcAbsDir", srcAbsDir), zap.Error(err))
-		return
-	}
-
-	for _, fileInfo := range files {
-		if !fileInfo.IsDir() {
-			fileName := fileInfo.Name()
-			data, err := ioutil.ReadFile(srcAbsDir + string(os.PathSeparator) + fileName)
-			if err != nil {
-				zap.L().Error("读取文件时异常，%v,%v", zap.String("文件全路径", srcAbsDir+string(os.PathSeparator)+fileName), zap.Error(err))
-			}
-			if err := ioutil.WriteFile(targetAbsDir+string(os.PathSeparator)+fileName, data, fileInfo.Mode()); err != nil {
-				zap.L().Error("写出文件时异常", zap.Error(err))
-			}
-		}
-		if fileInfo.IsDir() {
-			subfolder := fileInfo.Name()
-			CopyFolder(srcAbsDir+string(os.PathSeparator)+subfolder, targetAbsDir+string(os.PathSeparator)+subfolder, copySubfolder...)
-		}
-	}
+	defaultFS.CopyFolder(srcAbsDir, targetAbsDir, copySubfolder...)
 }
 
 func GetFileName(filePathName string) (dir, fileName string) {
@@ -246,95 +127,35 @@ func GetFileName(filePathName string) (dir, fileName string) {
 // 判断给定文件名是否是一个目录
 // 如果文件名存在并且为目录则返回 true。如果 filename 是一个相对路径，则按照当前工作目录检查其相对路径。
 func IsDir(filename string) bool {
-	return isFileOrDir(filename, true)
+	return defaultFS.IsDir(filename)
 }
 
 func IsFile(filename string) bool {
-	return isFileOrDir(filename, false)
-}
-
-// 判断是文件还是目录，根据decideDir为true表示判断是否为目录；否则判断是否为文件
-func isFileOrDir(filename string, decideDir bool) bool {
-	fileInfo, err := os.Stat(filename)
-	if err != nil {
-		return false
-	}
-	isDir := fileInfo.IsDir()
-	if decideDir {
-		return isDir
-	}
-	return !isDir
+	return defaultFS.IsFile(filename)
 }
 
 func CheckFileIsExist(filepath string) bool {
-	exist := true
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
-		exist = false
-	}
-	return exist
+	return defaultFS.CheckFileIsExist(filepath)
 }
 
 // 获得文件的修改时间
 func FileModTime(path string) (int64, error) {
-	f, err := os.Stat(path)
-	if err != nil {
-		return 0, err
-	}
-	return f.ModTime().Unix(), nil
+	return defaultFS.FileModTime(path)
 }
 
 // 返回文件的大小
 func FileSize(path string) (int64, error) {
-	f, err := os.Stat(path)
-	if err != nil {
-		return 0, err
-	}
-	return f.Size(), nil
+	return defaultFS.FileSize(path)
 }
 
 // 遍历目录及下级目录，查找符合后缀文件,如果suffix为空，则查找所有文件
 func GetFileListBySuffix(dirPath, suffix string) (files []string, err error) {
-	if !IsDir(dirPath) {
-		return nil, fmt.Errorf("given path does not exist: %s", dirPath)
-	}
-	files = make([]string, 0, 30)
-	suffix = strings.ToUpper(suffix)                                                      //忽略后缀匹配的大小写
-	err = filepath.Walk(dirPath, func(filename string, fi os.FileInfo, err error) error { //遍历目录
-		if err != nil { //忽略错误
-			return err
-		}
-		if fi.IsDir() { // 忽略目录
-			return nil
-		}
-		if strings.HasSuffix(strings.ToUpper(fi.Name()), suffix) {
-			files = append(files, filename)
-		}
-		return nil
-	})
-	return files, err
+	return defaultFS.GetFileListBySuffix(dirPath, suffix)
 }
 
 // 遍历指定目录下的所有文件，查找符合后缀文件,不进入下一级目录搜索
 func GetFileListJustCurrentDirBySuffix(dirPath string, suffix string) (files []string, err error) {
-	if !IsDir(dirPath) {
-		return nil, fmt.Errorf("given path does not exist: %s", dirPath)
-	}
-	files = make([]string, 0, 10)
-	dir, err := ioutil.ReadDir(dirPath)
-	if err != nil {
-		return nil, err
-	}
-	PathSep := string(os.PathSeparator)
-	suffix = strings.ToUpper(suffix) //忽略后缀匹配的大小写
-	for _, fi := range dir {
-		if fi.IsDir() { // 忽略目录
-			continue
-		}
-		if strings.HasSuffix(strings.ToUpper(fi.Name()), suffix) {
-			files = append(files, dirPath+PathSep+fi.Name())
-		}
-	}
-	return files, nil
+	return defaultFS.GetFileListJustCurrentDirBySuffix(dirPath, suffix)
 }
 
 // 把文件大小转换成人更加容易看懂的文本
@@ -427,4 +248,4 @@ func getCurrentDirectory() string {
 		// log.Fatal(err)
 	}
 	return strings.Replace(dir, "\\", "/", -1)
-}
\ No newline at end of file
+}